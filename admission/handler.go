@@ -0,0 +1,153 @@
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/MagalixTechnologies/core/logger"
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"github.com/MagalixTechnologies/policy-core/validation"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// TriggerAdmission is the default trigger entities are validated with
+	TriggerAdmission = "Admission"
+
+	maxRequestBodySize = 10 << 20
+)
+
+// Handler is an http.Handler serving admission reviews of a validating or mutating webhook
+type Handler struct {
+	validator validation.Validator
+	trigger   string
+	failOpen  bool
+}
+
+// NewHandler returns an admission webhook handler validating the reviewed objects using validator
+func NewHandler(validator validation.Validator) *Handler {
+	return &Handler{
+		validator: validator,
+		trigger:   TriggerAdmission,
+	}
+}
+
+// WithTrigger sets the trigger entities are validated with
+func (h *Handler) WithTrigger(trigger string) *Handler {
+	h.trigger = trigger
+	return h
+}
+
+// WithFailOpen allows the reviewed objects when the validation fails, objects are denied by default
+func (h *Handler) WithFailOpen(failOpen bool) *Handler {
+	h.failOpen = failOpen
+	return h
+}
+
+// ServeHTTP decodes an admission.k8s.io/v1 AdmissionReview and responds with the review result
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, fmt.Sprintf("method %s is not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	if contentType := r.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		http.Error(w, fmt.Sprintf("unsupported content type %s", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBodySize+1))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read request body: %s", err), http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxRequestBodySize {
+		http.Error(w, "request body is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(body, &review); err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode admission review: %s", err), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "admission review has no request", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = h.Review(r.Context(), review.Request)
+	review.Request = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		logger.Errorw("failed to write admission response", "error", err)
+	}
+}
+
+// Review validates the object of an admission request, the object is denied with the messages of its violations
+// and, when the validator mutated it, allowed with a JSONPatch fixing them
+func (h *Handler) Review(ctx context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	response := &admissionv1.AdmissionResponse{UID: req.UID}
+
+	entity, err := domain.NewEntityFromAdmissionRequest(req)
+	if err != nil {
+		return h.failed(response, err)
+	}
+
+	summary, err := h.validator.Validate(ctx, entity, h.trigger)
+	if err != nil {
+		return h.failed(response, err)
+	}
+
+	if len(summary.Violations) > 0 {
+		response.Allowed = false
+		response.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusForbidden,
+			Reason:  metav1.StatusReasonForbidden,
+			Message: strings.Join(summary.GetViolationOccurrencesMessages(), "\n"),
+		}
+		return response
+	}
+
+	response.Allowed = true
+	if summary.Mutation != nil {
+		newResource, err := summary.Mutation.NewResource()
+		if err != nil {
+			return h.failed(response, fmt.Errorf("failed to get mutated resource: %w", err))
+		}
+		patch, err := createPatch(summary.Mutation.OldResource(), newResource)
+		if err != nil {
+			return h.failed(response, fmt.Errorf("failed to create mutation patch: %w", err))
+		}
+		if patch != nil {
+			patchType := admissionv1.PatchTypeJSONPatch
+			response.Patch = patch
+			response.PatchType = &patchType
+		}
+	}
+	return response
+}
+
+// failed allows or denies the request after a validation error according to the handler failure policy
+func (h *Handler) failed(response *admissionv1.AdmissionResponse, err error) *admissionv1.AdmissionResponse {
+	logger.Errorw("failed to review admission request", "uid", response.UID, "failOpen", h.failOpen, "error", err)
+	if h.failOpen {
+		response.Allowed = true
+		response.Warnings = []string{fmt.Sprintf("policy validation failed: %s", err)}
+		return response
+	}
+	response.Allowed = false
+	response.Result = &metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusInternalServerError,
+		Reason:  metav1.StatusReasonInternalError,
+		Message: fmt.Sprintf("policy validation failed: %s", err),
+	}
+	return response
+}
@@ -0,0 +1,289 @@
+package admission
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	domainmock "github.com/MagalixTechnologies/policy-core/domain/mock"
+	"github.com/MagalixTechnologies/policy-core/validation"
+	"github.com/MagalixTechnologies/policy-core/validation/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const deployment = `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "app", "namespace": "default"}, "spec": {"replicas": 1}}`
+
+func newReview(dryRun bool) admissionv1.AdmissionReview {
+	return admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "705ab4f5-6393-11e8-b7cc-42010a800002",
+			Operation: admissionv1.Create,
+			DryRun:    &dryRun,
+			Object:    runtime.RawExtension{Raw: []byte(deployment)},
+		},
+	}
+}
+
+func serve(t *testing.T, handler http.Handler, review admissionv1.AdmissionReview) *admissionv1.AdmissionReview {
+	body, err := json.Marshal(review)
+	require.Nil(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var response admissionv1.AdmissionReview
+	require.Nil(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, review.TypeMeta, response.TypeMeta)
+	assert.Nil(t, response.Request)
+	require.NotNil(t, response.Response)
+	assert.Equal(t, review.Request.UID, response.Response.UID)
+	return &response
+}
+
+func TestHandler(t *testing.T) {
+	replicas := "spec.replicas"
+
+	tests := []struct {
+		name     string
+		failOpen bool
+		summary  func(entity domain.Entity) *domain.PolicyValidationSummary
+		err      error
+		allowed  bool
+		code     int32
+		message  string
+		warnings []string
+		patch    string
+	}{
+		{
+			name: "compliant",
+			summary: func(entity domain.Entity) *domain.PolicyValidationSummary {
+				return &domain.PolicyValidationSummary{
+					Compliances: []domain.PolicyValidation{{Status: domain.PolicyValidationStatusCompliant}},
+				}
+			},
+			allowed: true,
+		},
+		{
+			name: "violating",
+			summary: func(entity domain.Entity) *domain.PolicyValidationSummary {
+				return &domain.PolicyValidationSummary{
+					Violations: []domain.PolicyValidation{
+						{
+							Status: domain.PolicyValidationStatusViolating,
+							Occurrences: []domain.Occurrence{
+								{Message: "replicas must be at least 2"},
+								{Message: "owner label is missing"},
+							},
+						},
+						{
+							Status:      domain.PolicyValidationStatusViolating,
+							Occurrences: []domain.Occurrence{{Message: "image tag must not be latest"}},
+						},
+					},
+				}
+			},
+			code:    http.StatusForbidden,
+			message: "replicas must be at least 2\nowner label is missing\nimage tag must not be latest",
+		},
+		{
+			name: "mutated",
+			summary: func(entity domain.Entity) *domain.PolicyValidationSummary {
+				mutation, err := domain.NewMutationResult(entity)
+				require.Nil(t, err)
+				_, err = mutation.Mutate([]domain.Occurrence{{ViolatingKey: &replicas, RecommendedValue: 2}})
+				require.Nil(t, err)
+				return &domain.PolicyValidationSummary{Mutation: mutation}
+			},
+			allowed: true,
+			patch: `[
+				{"op": "add", "path": "/metadata/labels", "value": {"pac.weave.works/mutated": ""}},
+				{"op": "replace", "path": "/spec/replicas", "value": 2}
+			]`,
+		},
+		{
+			name:    "fail closed",
+			err:     errors.New("policies source is unavailable"),
+			code:    http.StatusInternalServerError,
+			message: "policy validation failed: policies source is unavailable",
+		},
+		{
+			name:     "fail open",
+			failOpen: true,
+			err:      errors.New("policies source is unavailable"),
+			allowed:  true,
+			warnings: []string{"policy validation failed: policies source is unavailable"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			validator := mock.NewMockValidator(ctrl)
+			validator.EXPECT().Validate(gomock.Any(), gomock.Any(), TriggerAdmission).
+				DoAndReturn(func(ctx context.Context, entity domain.Entity, trigger string) (*domain.PolicyValidationSummary, error) {
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return tt.summary(entity), nil
+				})
+
+			review := serve(t, NewHandler(validator).WithFailOpen(tt.failOpen), newReview(false))
+			response := review.Response
+
+			assert.Equal(t, tt.allowed, response.Allowed)
+			assert.Equal(t, tt.warnings, response.Warnings)
+			if tt.allowed {
+				assert.Nil(t, response.Result)
+			} else {
+				require.NotNil(t, response.Result)
+				assert.Equal(t, tt.code, response.Result.Code)
+				assert.Equal(t, tt.message, response.Result.Message)
+			}
+			if tt.patch == "" {
+				assert.Nil(t, response.Patch)
+				assert.Nil(t, response.PatchType)
+			} else {
+				require.NotNil(t, response.PatchType)
+				assert.Equal(t, admissionv1.PatchTypeJSONPatch, *response.PatchType)
+				assert.JSONEq(t, tt.patch, string(response.Patch))
+			}
+		})
+	}
+}
+
+func TestHandler_MutatingValidator(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	replicas := domain.Policy{
+		ID:       "min-replicas",
+		Name:     "Minimum replica count",
+		Severity: domain.PolicySeverityHigh,
+		Code: `
+		package weave.advisor.pods.replica_count
+
+		violation[result] {
+			input.review.object.spec.replicas < 3
+			result = {
+				"issue detected": true,
+				"msg": "Replica count must be at least 3",
+				"violating_key": "spec.replicas"
+			}
+		}
+		`,
+	}
+	policiesSource := domainmock.NewMockPoliciesSource(ctrl)
+	policiesSource.EXPECT().GetAll(gomock.Any()).Return([]domain.Policy{replicas}, nil)
+	policiesSource.EXPECT().GetPolicyConfig(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+	// violations of non mutating policies must deny the object even when the validator mutates
+	validator := validation.NewOPAValidator(policiesSource, false, "unit-test", "", "", true)
+	response := serve(t, NewHandler(validator), newReview(false)).Response
+
+	assert.False(t, response.Allowed)
+	require.NotNil(t, response.Result)
+	assert.Equal(t, int32(http.StatusForbidden), response.Result.Code)
+	assert.Contains(t, response.Result.Message, "Replica count must be at least 3")
+	assert.Nil(t, response.Patch)
+}
+
+func TestHandler_Entity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	validator := mock.NewMockValidator(ctrl)
+	validator.EXPECT().Validate(gomock.Any(), gomock.Any(), "Audit").
+		DoAndReturn(func(ctx context.Context, entity domain.Entity, trigger string) (*domain.PolicyValidationSummary, error) {
+			assert.Equal(t, "app", entity.Name)
+			assert.Equal(t, "default", entity.Namespace)
+			assert.Equal(t, "Deployment", entity.Kind)
+			require.NotNil(t, entity.Admission)
+			assert.Equal(t, "705ab4f5-6393-11e8-b7cc-42010a800002", entity.Admission.UID)
+			assert.True(t, entity.Admission.DryRun)
+			return &domain.PolicyValidationSummary{}, nil
+		})
+
+	review := serve(t, NewHandler(validator).WithTrigger("Audit"), newReview(true))
+	assert.True(t, review.Response.Allowed)
+}
+
+func TestHandler_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		code        int
+	}{
+		{
+			name:        "method not allowed",
+			method:      http.MethodGet,
+			contentType: "application/json",
+			code:        http.StatusMethodNotAllowed,
+		},
+		{
+			name:        "unsupported content type",
+			method:      http.MethodPost,
+			contentType: "text/plain",
+			body:        "{}",
+			code:        http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "invalid body",
+			method:      http.MethodPost,
+			contentType: "application/json",
+			body:        "{",
+			code:        http.StatusBadRequest,
+		},
+		{
+			name:        "missing request",
+			method:      http.MethodPost,
+			contentType: "application/json",
+			body:        `{"apiVersion": "admission.k8s.io/v1", "kind": "AdmissionReview"}`,
+			code:        http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			req := httptest.NewRequest(tt.method, "/validate", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			NewHandler(mock.NewMockValidator(ctrl)).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.code, rec.Code)
+		})
+	}
+}
+
+func TestHandler_InvalidObject(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	review := newReview(false)
+	review.Request.Object = runtime.RawExtension{Raw: []byte(`{"kind": "Deployment"}`)}
+
+	response := serve(t, NewHandler(mock.NewMockValidator(ctrl)), review).Response
+	assert.False(t, response.Allowed)
+	require.NotNil(t, response.Result)
+	assert.Equal(t, int32(http.StatusInternalServerError), response.Result.Code)
+}
@@ -0,0 +1,108 @@
+package admission
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	patchOpAdd     = "add"
+	patchOpRemove  = "remove"
+	patchOpReplace = "replace"
+)
+
+// patchOperation is a JSONPatch operation as defined by RFC 6902
+type patchOperation struct {
+	Op    string
+	Path  string
+	Value interface{}
+}
+
+// MarshalJSON omits the value of remove operations only, as add and replace may set null values
+func (o patchOperation) MarshalJSON() ([]byte, error) {
+	if o.Op == patchOpRemove {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{o.Op, o.Path})
+	}
+	return json.Marshal(struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}{o.Op, o.Path, o.Value})
+}
+
+// createPatch returns the JSONPatch transforming the old json document into the new one
+func createPatch(oldRaw, newRaw []byte) ([]byte, error) {
+	var oldDoc, newDoc interface{}
+	if err := json.Unmarshal(oldRaw, &oldDoc); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(newRaw, &newDoc); err != nil {
+		return nil, err
+	}
+	operations := diff("", oldDoc, newDoc, nil)
+	if len(operations) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(operations)
+}
+
+// diff appends the operations changing the old value at path into the new value, objects are compared by key,
+// arrays of the same length by index and any other change replaces the whole value
+func diff(path string, oldValue, newValue interface{}, operations []patchOperation) []patchOperation {
+	if reflect.DeepEqual(oldValue, newValue) {
+		return operations
+	}
+
+	switch oldTyped := oldValue.(type) {
+	case map[string]interface{}:
+		newTyped, ok := newValue.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for _, key := range sortedKeys(oldTyped) {
+			if _, ok := newTyped[key]; !ok {
+				operations = append(operations, patchOperation{Op: patchOpRemove, Path: path + "/" + escapeKey(key)})
+			}
+		}
+		for _, key := range sortedKeys(newTyped) {
+			keyPath := path + "/" + escapeKey(key)
+			if oldKeyValue, ok := oldTyped[key]; ok {
+				operations = diff(keyPath, oldKeyValue, newTyped[key], operations)
+			} else {
+				operations = append(operations, patchOperation{Op: patchOpAdd, Path: keyPath, Value: newTyped[key]})
+			}
+		}
+		return operations
+	case []interface{}:
+		newTyped, ok := newValue.([]interface{})
+		if !ok || len(oldTyped) != len(newTyped) {
+			break
+		}
+		for i := range oldTyped {
+			operations = diff(path+"/"+strconv.Itoa(i), oldTyped[i], newTyped[i], operations)
+		}
+		return operations
+	}
+
+	return append(operations, patchOperation{Op: patchOpReplace, Path: path, Value: newValue})
+}
+
+// escapeKey escapes a key to be used as a JSON pointer reference token
+func escapeKey(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,75 @@
+package admission
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreatePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		old   string
+		new   string
+		patch string
+	}{
+		{
+			name: "no changes",
+			old:  `{"spec": {"replicas": 1}}`,
+			new:  `{"spec": {"replicas": 1}}`,
+		},
+		{
+			name:  "replace value",
+			old:   `{"spec": {"replicas": 1}}`,
+			new:   `{"spec": {"replicas": 3}}`,
+			patch: `[{"op": "replace", "path": "/spec/replicas", "value": 3}]`,
+		},
+		{
+			name:  "add and remove keys",
+			old:   `{"metadata": {"labels": {"app": "web", "team": "a"}}}`,
+			new:   `{"metadata": {"labels": {"app": "web", "owner": "b"}}}`,
+			patch: `[{"op": "remove", "path": "/metadata/labels/team"}, {"op": "add", "path": "/metadata/labels/owner", "value": "b"}]`,
+		},
+		{
+			name:  "escaped keys",
+			old:   `{"metadata": {"labels": {}}}`,
+			new:   `{"metadata": {"labels": {"pac.weave.works/mutated": "", "a~b": "c"}}}`,
+			patch: `[{"op": "add", "path": "/metadata/labels/a~0b", "value": "c"}, {"op": "add", "path": "/metadata/labels/pac.weave.works~1mutated", "value": ""}]`,
+		},
+		{
+			name:  "arrays of the same length",
+			old:   `{"containers": [{"name": "a", "privileged": true}, {"name": "b"}]}`,
+			new:   `{"containers": [{"name": "a", "privileged": false}, {"name": "b"}]}`,
+			patch: `[{"op": "replace", "path": "/containers/0/privileged", "value": false}]`,
+		},
+		{
+			name:  "arrays of different lengths",
+			old:   `{"args": ["a"]}`,
+			new:   `{"args": ["a", "b"]}`,
+			patch: `[{"op": "replace", "path": "/args", "value": ["a", "b"]}]`,
+		},
+		{
+			name:  "null values",
+			old:   `{"spec": {"replicas": 1}}`,
+			new:   `{"spec": {"replicas": null, "paused": null}}`,
+			patch: `[{"op": "add", "path": "/spec/paused", "value": null}, {"op": "replace", "path": "/spec/replicas", "value": null}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := createPatch([]byte(tt.old), []byte(tt.new))
+			assert.Nil(t, err)
+			if tt.patch == "" {
+				assert.Nil(t, patch)
+				return
+			}
+			assert.JSONEq(t, tt.patch, string(patch))
+		})
+	}
+}
+
+func TestCreatePatch_InvalidJSON(t *testing.T) {
+	_, err := createPatch([]byte(`{`), []byte(`{}`))
+	assert.Error(t, err)
+}
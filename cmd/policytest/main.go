@@ -0,0 +1,46 @@
+// Command policytest runs policies test suites and reports the cases not matching their expectations
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/MagalixTechnologies/policy-core/policytest"
+)
+
+func main() {
+	policiesPath := flag.String("policies", "", "path of the json or yaml file holding the policies definitions")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s -policies <file> <suite>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *policiesPath == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	policies, err := policytest.LoadPolicies(*policiesPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var suites []*policytest.Suite
+	for _, path := range flag.Args() {
+		suite, err := policytest.LoadSuite(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		suites = append(suites, suite)
+	}
+
+	report := policytest.Run(context.Background(), policies, suites...)
+	report.Print(os.Stdout)
+	if report.Failed() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	CloudEventsSpecVersion    = "1.0"
+	CloudEventTypeViolation   = "works.weave.pac.validation.violation"
+	CloudEventTypeCompliance  = "works.weave.pac.validation.compliance"
+	CloudEventTypeResolved    = "works.weave.pac.validation.resolved"
+	CloudEventDataContentType = "application/json"
+)
+
+const (
+	cloudEventSourceAccountsPath = "/accounts/"
+	cloudEventSourceClustersPath = "/clusters/"
+)
+
+// CloudEvent represents a CloudEvents 1.0 event
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+var cloudEventTypes = map[string]string{
+	PolicyValidationStatusViolating: CloudEventTypeViolation,
+	PolicyValidationStatusCompliant: CloudEventTypeCompliance,
+	PolicyValidationStatusResolved:  CloudEventTypeResolved,
+}
+
+// NewCloudEventFromPolicyValidation gets cloud event from policy validation result object,
+// the event data is the policy validation result in json
+func NewCloudEventFromPolicyValidation(result PolicyValidation) (CloudEvent, error) {
+	eventType, ok := cloudEventTypes[result.Status]
+	if !ok {
+		return CloudEvent{}, fmt.Errorf("unknown policy validation status %s", result.Status)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return CloudEvent{}, fmt.Errorf("failed to marshal policy validation: %w", err)
+	}
+
+	createdAt := result.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	return CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              result.ID,
+		Source:          cloudEventSource(result.AccountID, result.ClusterID),
+		Type:            eventType,
+		Subject:         cloudEventSubject(result.Entity),
+		Time:            createdAt.UTC(),
+		DataContentType: CloudEventDataContentType,
+		Data:            data,
+	}, nil
+}
+
+// NewPolicyValidationFromCloudEvent gets policy validation result object from cloud event
+func NewPolicyValidationFromCloudEvent(event CloudEvent) (PolicyValidation, error) {
+	var result PolicyValidation
+	if event.SpecVersion != CloudEventsSpecVersion {
+		return result, fmt.Errorf("unsupported cloud events spec version %s", event.SpecVersion)
+	}
+
+	var status string
+	for eventStatus, eventType := range cloudEventTypes {
+		if eventType == event.Type {
+			status = eventStatus
+			break
+		}
+	}
+	if status == "" {
+		return result, fmt.Errorf("unknown cloud event type %s", event.Type)
+	}
+
+	if len(event.Data) > 0 {
+		err := json.Unmarshal(event.Data, &result)
+		if err != nil {
+			return result, fmt.Errorf("failed to get policy validation from event data: %w", err)
+		}
+	}
+
+	result.ID = event.ID
+	result.Status = status
+	result.CreatedAt = event.Time
+	if accountID, clusterID, ok := parseCloudEventSource(event.Source); ok {
+		result.AccountID = accountID
+		result.ClusterID = clusterID
+	}
+	return result, nil
+}
+
+func cloudEventSource(accountID, clusterID string) string {
+	return cloudEventSourceAccountsPath + accountID + cloudEventSourceClustersPath + clusterID
+}
+
+func parseCloudEventSource(source string) (string, string, bool) {
+	if !strings.HasPrefix(source, cloudEventSourceAccountsPath) {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(source, cloudEventSourceAccountsPath), cloudEventSourceClustersPath, 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func cloudEventSubject(entity Entity) string {
+	if entity.Namespace == "" {
+		return fmt.Sprintf("%s/%s", entity.Kind, entity.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", entity.Kind, entity.Namespace, entity.Name)
+}
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudEvent(t *testing.T) {
+	createdAt := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	result := PolicyValidation{
+		ID:        "result-1",
+		AccountID: "account-1",
+		ClusterID: "cluster-1",
+		Policy: Policy{
+			ID:       "policy-1",
+			Name:     "my-policy",
+			Severity: "high",
+		},
+		Entity: Entity{
+			ID:        "entity-1",
+			Kind:      "Deployment",
+			Name:      "nginx",
+			Namespace: "default",
+			Manifest:  map[string]interface{}{"kind": "Deployment"},
+		},
+		Status:      PolicyValidationStatusViolating,
+		Message:     "message",
+		Occurrences: []Occurrence{{Message: "occurrence"}},
+		Type:        "Audit",
+		Trigger:     "PolicyChange",
+		CreatedAt:   createdAt,
+	}
+
+	event, err := NewCloudEventFromPolicyValidation(result)
+	assert.Nil(t, err)
+	assert.Equal(t, CloudEventsSpecVersion, event.SpecVersion)
+	assert.Equal(t, "result-1", event.ID)
+	assert.Equal(t, CloudEventTypeViolation, event.Type)
+	assert.Equal(t, "/accounts/account-1/clusters/cluster-1", event.Source)
+	assert.Equal(t, "Deployment/default/nginx", event.Subject)
+	assert.Equal(t, createdAt, event.Time)
+
+	raw, err := json.Marshal(event)
+	assert.Nil(t, err)
+	var decoded CloudEvent
+	assert.Nil(t, json.Unmarshal(raw, &decoded))
+
+	got, err := NewPolicyValidationFromCloudEvent(decoded)
+	assert.Nil(t, err)
+	assert.Equal(t, result.ID, got.ID)
+	assert.Equal(t, result.AccountID, got.AccountID)
+	assert.Equal(t, result.ClusterID, got.ClusterID)
+	assert.Equal(t, result.Status, got.Status)
+	assert.Equal(t, result.Policy.ID, got.Policy.ID)
+	assert.Equal(t, result.Entity.Name, got.Entity.Name)
+	assert.Equal(t, result.Occurrences, got.Occurrences)
+	assert.Equal(t, result.Trigger, got.Trigger)
+	assert.True(t, result.CreatedAt.Equal(got.CreatedAt))
+
+	compliance := result
+	compliance.Status = PolicyValidationStatusCompliant
+	compliance.Entity.Namespace = ""
+	event, err = NewCloudEventFromPolicyValidation(compliance)
+	assert.Nil(t, err)
+	assert.Equal(t, CloudEventTypeCompliance, event.Type)
+	assert.Equal(t, "Deployment/nginx", event.Subject)
+
+	_, err = NewCloudEventFromPolicyValidation(PolicyValidation{Status: "unknown"})
+	assert.Error(t, err)
+	_, err = NewPolicyValidationFromCloudEvent(CloudEvent{SpecVersion: CloudEventsSpecVersion, Type: "unknown"})
+	assert.Error(t, err)
+	_, err = NewPolicyValidationFromCloudEvent(CloudEvent{SpecVersion: "0.3", Type: CloudEventTypeViolation})
+	assert.Error(t, err)
+}
@@ -0,0 +1,164 @@
+package domain
+
+import "sort"
+
+// ComplianceScore counts the compliant and violating results of a group of policies
+type ComplianceScore struct {
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+}
+
+// Total returns the number of counted results
+func (s ComplianceScore) Total() int {
+	return s.Passed + s.Failed
+}
+
+// Percentage returns the percentage of compliant results, 0 when there are no results
+func (s ComplianceScore) Percentage() float64 {
+	if s.Total() == 0 {
+		return 0
+	}
+	return float64(s.Passed) * 100 / float64(s.Total())
+}
+
+func (s *ComplianceScore) add(result PolicyValidation) {
+	switch result.Status {
+	case PolicyValidationStatusCompliant:
+		s.Passed++
+	case PolicyValidationStatusViolating:
+		s.Failed++
+	}
+}
+
+// ControlCompliance is the compliance score of a standard control
+type ControlCompliance struct {
+	ID string `json:"id"`
+	ComplianceScore
+}
+
+// StandardCompliance is the compliance score of a standard and its controls
+type StandardCompliance struct {
+	ID string `json:"id"`
+	ComplianceScore
+	Controls []ControlCompliance `json:"controls"`
+}
+
+// ComplianceReport holds the compliance scores of the standards of a set of policies
+type ComplianceReport struct {
+	Standards []StandardCompliance `json:"standards"`
+
+	policies map[string]Policy
+	results  []PolicyValidation
+}
+
+// NewComplianceReport computes the compliance scores of the policies standards and controls from the validation results.
+// Results are mapped to standards through the given policies, falling back to the result policy when it is not found,
+// and results other than violations and compliances are ignored.
+func NewComplianceReport(policies []Policy, results []PolicyValidation) *ComplianceReport {
+	report := &ComplianceReport{
+		policies: make(map[string]Policy, len(policies)),
+		results:  results,
+	}
+	for _, policy := range policies {
+		report.policies[policy.ID] = policy
+	}
+
+	standards := map[string]*StandardCompliance{}
+	controls := map[string]map[string]*ControlCompliance{}
+	addStandard := func(standard PolicyStandard) {
+		if _, ok := standards[standard.ID]; !ok {
+			standards[standard.ID] = &StandardCompliance{ID: standard.ID}
+			controls[standard.ID] = map[string]*ControlCompliance{}
+		}
+		for _, control := range standard.Controls {
+			if _, ok := controls[standard.ID][control]; !ok {
+				controls[standard.ID][control] = &ControlCompliance{ID: control}
+			}
+		}
+	}
+
+	// policies without results are still listed with empty scores
+	for _, policy := range policies {
+		for _, standard := range policy.Standards {
+			addStandard(standard)
+		}
+	}
+	for _, result := range results {
+		for _, standard := range report.policyOf(result).Standards {
+			addStandard(standard)
+			standards[standard.ID].add(result)
+			for _, control := range uniqueStrings(standard.Controls) {
+				controls[standard.ID][control].add(result)
+			}
+		}
+	}
+
+	for id, standard := range standards {
+		for _, control := range controls[id] {
+			standard.Controls = append(standard.Controls, *control)
+		}
+		sort.Slice(standard.Controls, func(i, j int) bool {
+			return standard.Controls[i].ID < standard.Controls[j].ID
+		})
+		report.Standards = append(report.Standards, *standard)
+	}
+	sort.Slice(report.Standards, func(i, j int) bool {
+		return report.Standards[i].ID < report.Standards[j].ID
+	})
+	return report
+}
+
+// Standard returns the compliance of a standard, nil if no policy belongs to it
+func (r *ComplianceReport) Standard(id string) *StandardCompliance {
+	for i := range r.Standards {
+		if r.Standards[i].ID == id {
+			return &r.Standards[i]
+		}
+	}
+	return nil
+}
+
+// Score returns the compliance score of the results of policies having a control of the standard
+// matching the control pattern, e.g. 5.2.* for all CIS 5.2 controls. Each result is counted once
+// even when its policy has multiple matching controls.
+func (r *ComplianceReport) Score(standardID, controlPattern string) ComplianceScore {
+	var score ComplianceScore
+	for _, result := range r.results {
+		for _, standard := range r.policyOf(result).Standards {
+			if standard.ID == standardID && matchControl(standard, controlPattern) {
+				score.add(result)
+				break
+			}
+		}
+	}
+	return score
+}
+
+func matchControl(standard PolicyStandard, controlPattern string) bool {
+	for _, control := range standard.Controls {
+		if matchPattern(controlPattern, control) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ComplianceReport) policyOf(result PolicyValidation) Policy {
+	if policy, ok := r.policies[result.Policy.ID]; ok {
+		return policy
+	}
+	return result.Policy
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	var unique []string
+	for _, value := range values {
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		unique = append(unique, value)
+	}
+	return unique
+}
@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewComplianceReport(t *testing.T) {
+	imageTag := Policy{
+		ID: "image-tag",
+		Standards: []PolicyStandard{
+			{ID: "cis", Controls: []string{"5.2.1", "5.2.6"}},
+			{ID: "soc2", Controls: []string{"6.1"}},
+		},
+	}
+	privileged := Policy{
+		ID:        "privileged",
+		Standards: []PolicyStandard{{ID: "cis", Controls: []string{"5.2.1"}}},
+	}
+	clusterAdmin := Policy{
+		ID:        "cluster-admin",
+		Standards: []PolicyStandard{{ID: "cis", Controls: []string{"5.1.1"}}},
+	}
+	noStandards := Policy{ID: "no-standards"}
+	unknown := Policy{
+		ID:        "unknown",
+		Standards: []PolicyStandard{{ID: "nist", Controls: []string{"ac-6"}}},
+	}
+
+	result := func(policy Policy, status string) PolicyValidation {
+		// results carry a copy of the policy without its standards
+		return PolicyValidation{Policy: Policy{ID: policy.ID}, Status: status}
+	}
+	results := []PolicyValidation{
+		result(imageTag, PolicyValidationStatusViolating),
+		result(imageTag, PolicyValidationStatusCompliant),
+		result(privileged, PolicyValidationStatusCompliant),
+		result(privileged, PolicyValidationStatusCompliant),
+		result(privileged, PolicyValidationStatusResolved),
+		result(noStandards, PolicyValidationStatusViolating),
+		{Policy: unknown, Status: PolicyValidationStatusViolating},
+	}
+
+	report := NewComplianceReport([]Policy{imageTag, privileged, clusterAdmin, noStandards}, results)
+	assert.Equal(t, []StandardCompliance{
+		{
+			ID:              "cis",
+			ComplianceScore: ComplianceScore{Passed: 3, Failed: 1},
+			Controls: []ControlCompliance{
+				{ID: "5.1.1"},
+				{ID: "5.2.1", ComplianceScore: ComplianceScore{Passed: 3, Failed: 1}},
+				{ID: "5.2.6", ComplianceScore: ComplianceScore{Passed: 1, Failed: 1}},
+			},
+		},
+		{
+			ID:              "nist",
+			ComplianceScore: ComplianceScore{Failed: 1},
+			Controls:        []ControlCompliance{{ID: "ac-6", ComplianceScore: ComplianceScore{Failed: 1}}},
+		},
+		{
+			ID:              "soc2",
+			ComplianceScore: ComplianceScore{Passed: 1, Failed: 1},
+			Controls:        []ControlCompliance{{ID: "6.1", ComplianceScore: ComplianceScore{Passed: 1, Failed: 1}}},
+		},
+	}, report.Standards)
+
+	cis := report.Standard("cis")
+	assert.NotNil(t, cis)
+	assert.Equal(t, 75.0, cis.Percentage())
+	assert.Equal(t, 0.0, cis.Controls[0].Percentage())
+	assert.Nil(t, report.Standard("pci-dss"))
+
+	// image-tag has two matching controls but its results are counted once
+	assert.Equal(t, ComplianceScore{Passed: 3, Failed: 1}, report.Score("cis", "5.2.*"))
+	assert.Equal(t, ComplianceScore{Passed: 1, Failed: 1}, report.Score("cis", "5.2.6"))
+	assert.Equal(t, ComplianceScore{}, report.Score("cis", "5.1.*"))
+	assert.Equal(t, ComplianceScore{}, report.Score("soc2", "5.2.*"))
+}
+
+func TestPolicySet_Controls(t *testing.T) {
+	policy := Policy{
+		ID:        "privileged",
+		Severity:  "high",
+		Standards: []PolicyStandard{{ID: "cis", Controls: []string{"5.2.1", "5.2.5"}}},
+	}
+
+	tests := []struct {
+		name    string
+		filters PolicySetFilters
+		match   bool
+	}{
+		{name: "control", filters: PolicySetFilters{Controls: []string{"5.2.5"}}, match: true},
+		{name: "control glob", filters: PolicySetFilters{Controls: []string{"5.2.*"}}, match: true},
+		{name: "other control", filters: PolicySetFilters{Controls: []string{"5.1.1"}}},
+		{
+			name: "control and severity",
+			filters: PolicySetFilters{And: []PolicySetFilters{
+				{Controls: []string{"5.2.*"}},
+				{Severities: []string{"low"}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, tt.filters.Match(policy))
+		})
+	}
+}
@@ -20,6 +20,16 @@ type Entity struct {
 	Labels          map[string]string      `json:"-"`
 	GitCommit       string                 `json:"-"`
 	HasParent       bool                   `json:"has_parent"`
+	// Source is the position of the entity in the file it was parsed from, if any
+	Source *EntitySource `json:"source,omitempty"`
+	// Admission holds the admission request the entity was received in, if any
+	Admission *EntityAdmission `json:"admission,omitempty"`
+}
+
+// EntitySource is the position of an entity in a file
+type EntitySource struct {
+	File string `json:"file,omitempty"`
+	Line int    `json:"line"`
 }
 
 // ObjectRef returns the kubernetes object reference of the entity
@@ -34,11 +44,13 @@ func (e *Entity) ObjectRef() *v1.ObjectReference {
 	}
 }
 
-// NewEntityFromSpec takes map representing a Kubernetes entity and parses it into Entity struct
+// NewEntityFromSpec takes map representing a Kubernetes entity and parses it into Entity struct,
+// use NewEntityFromManifest to have the map validated
 func NewEntityFromSpec(entitySpec map[string]interface{}) Entity {
 	kubeEntity := unstructured.Unstructured{Object: entitySpec}
-	metadata := entitySpec["metadata"].(map[string]interface{})
-	delete(metadata, "managedFields")
+	if metadata, ok := entitySpec["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "managedFields")
+	}
 	return Entity{
 		ID:              string(kubeEntity.GetUID()),
 		Name:            kubeEntity.GetName(),
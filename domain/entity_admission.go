@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+// EntityAdmission holds the details of the admission request an entity was received in
+type EntityAdmission struct {
+	UID         string                    `json:"uid"`
+	Operation   string                    `json:"operation"`
+	UserInfo    authenticationv1.UserInfo `json:"user_info"`
+	DryRun      bool                      `json:"dry_run"`
+	SubResource string                    `json:"sub_resource,omitempty"`
+	// OldObject is the existing object for UPDATE and DELETE operations
+	OldObject map[string]interface{} `json:"-"`
+}
+
+// NewEntityFromAdmissionRequest parses the object of an admission request into Entity, the old object is
+// used for DELETE operations. The request details are set as the entity admission.
+func NewEntityFromAdmissionRequest(req *admissionv1.AdmissionRequest) (Entity, error) {
+	if req == nil {
+		return Entity{}, errors.New("admission request is empty")
+	}
+
+	raw := req.Object.Raw
+	if req.Operation == admissionv1.Delete || len(raw) == 0 {
+		raw = req.OldObject.Raw
+	}
+	if len(raw) == 0 {
+		return Entity{}, fmt.Errorf("admission request %s has no object", req.UID)
+	}
+
+	var manifest map[string]interface{}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return Entity{}, fmt.Errorf("failed to parse object of admission request %s: %w", req.UID, err)
+	}
+	entity, err := NewEntityFromManifest(manifest)
+	if err != nil {
+		return Entity{}, fmt.Errorf("invalid object of admission request %s: %w", req.UID, err)
+	}
+	// objects may rely on the request for their namespace or, when generated, their name
+	if entity.Namespace == "" {
+		entity.Namespace = req.Namespace
+	}
+	if entity.Name == "" {
+		entity.Name = req.Name
+	}
+
+	admission := &EntityAdmission{
+		UID:         string(req.UID),
+		Operation:   string(req.Operation),
+		UserInfo:    req.UserInfo,
+		SubResource: req.SubResource,
+	}
+	if req.DryRun != nil {
+		admission.DryRun = *req.DryRun
+	}
+	if len(req.OldObject.Raw) > 0 {
+		if err := json.Unmarshal(req.OldObject.Raw, &admission.OldObject); err != nil {
+			return Entity{}, fmt.Errorf("failed to parse old object of admission request %s: %w", req.UID, err)
+		}
+	}
+	entity.Admission = admission
+	return entity, nil
+}
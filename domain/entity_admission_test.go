@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestNewEntityFromAdmissionRequest(t *testing.T) {
+	object := `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "app", "namespace": "default"}, "spec": {"replicas": 3}}`
+	oldObject := `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "app", "namespace": "default"}, "spec": {"replicas": 1}}`
+	dryRun := true
+
+	tests := []struct {
+		name     string
+		req      *admissionv1.AdmissionRequest
+		replicas float64
+		old      bool
+		err      string
+	}{
+		{
+			name: "create",
+			req: &admissionv1.AdmissionRequest{
+				UID:       "1",
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: []byte(object)},
+			},
+			replicas: 3,
+		},
+		{
+			name: "update",
+			req: &admissionv1.AdmissionRequest{
+				UID:         "2",
+				Operation:   admissionv1.Update,
+				SubResource: "scale",
+				DryRun:      &dryRun,
+				UserInfo:    authenticationv1.UserInfo{Username: "jane", Groups: []string{"devs"}},
+				Object:      runtime.RawExtension{Raw: []byte(object)},
+				OldObject:   runtime.RawExtension{Raw: []byte(oldObject)},
+			},
+			replicas: 3,
+			old:      true,
+		},
+		{
+			name: "delete",
+			req: &admissionv1.AdmissionRequest{
+				UID:       "3",
+				Operation: admissionv1.Delete,
+				OldObject: runtime.RawExtension{Raw: []byte(oldObject)},
+			},
+			replicas: 1,
+			old:      true,
+		},
+		{name: "nil request", err: "admission request is empty"},
+		{
+			name: "no object",
+			req:  &admissionv1.AdmissionRequest{UID: "4", Operation: admissionv1.Create},
+			err:  "admission request 4 has no object",
+		},
+		{
+			name: "invalid object",
+			req: &admissionv1.AdmissionRequest{
+				UID:       "5",
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: []byte(`{"kind": "Deployment"}`)},
+			},
+			err: "invalid object of admission request 5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entity, err := NewEntityFromAdmissionRequest(tt.req)
+			if tt.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "app", entity.Name)
+			assert.Equal(t, "Deployment", entity.Kind)
+			assert.Equal(t, tt.replicas, entity.Manifest["spec"].(map[string]interface{})["replicas"])
+			assert.Equal(t, string(tt.req.UID), entity.Admission.UID)
+			assert.Equal(t, string(tt.req.Operation), entity.Admission.Operation)
+			assert.Equal(t, tt.req.UserInfo, entity.Admission.UserInfo)
+			assert.Equal(t, tt.req.SubResource, entity.Admission.SubResource)
+			assert.Equal(t, tt.req.DryRun != nil, entity.Admission.DryRun)
+			assert.Equal(t, tt.old, entity.Admission.OldObject != nil)
+		})
+	}
+}
+
+func TestNewEntityFromAdmissionRequest_RequestMetadata(t *testing.T) {
+	entity, err := NewEntityFromAdmissionRequest(&admissionv1.AdmissionRequest{
+		Name:      "pod-x7k2p",
+		Namespace: "team-a",
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"generateName": "pod-"}}`)},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "pod-x7k2p", entity.Name)
+	assert.Equal(t, "team-a", entity.Namespace)
+}
@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const listKindSuffix = "List"
+
+// NewEntityFromManifest takes map representing a Kubernetes entity and parses it into Entity struct,
+// it returns an error when the map is not a kubernetes object
+func NewEntityFromManifest(manifest map[string]interface{}) (Entity, error) {
+	if manifest == nil {
+		return Entity{}, errors.New("manifest is empty")
+	}
+	if _, ok := manifest["metadata"].(map[string]interface{}); !ok {
+		return Entity{}, errors.New("metadata is missing or is not an object")
+	}
+	if kind, _ := manifest["kind"].(string); kind == "" {
+		return Entity{}, errors.New("kind is missing or is not a string")
+	}
+	if apiVersion, _ := manifest["apiVersion"].(string); apiVersion == "" {
+		return Entity{}, errors.New("apiVersion is missing or is not a string")
+	}
+	return NewEntityFromSpec(manifest), nil
+}
+
+// NewEntitiesFromFile parses the json or yaml documents of a file into entities, see NewEntitiesFromYAML
+func NewEntitiesFromFile(path string) ([]Entity, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return NewEntitiesFromYAML(raw, path)
+}
+
+// NewEntitiesFromYAML parses a stream of json or yaml documents separated by --- into entities.
+// Items of List kinds are returned as separate entities, empty documents are skipped and
+// each entity source is set to the given file and the line the entity starts at.
+func NewEntitiesFromYAML(raw []byte, file string) ([]Entity, error) {
+	name := file
+	if name == "" {
+		name = "input"
+	}
+
+	var entities []Entity
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	for index := 0; ; index++ {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse document %d of %s: %w", index, name, err)
+		}
+		documentEntities, err := newEntitiesFromNode(&node, file)
+		if err != nil {
+			return nil, fmt.Errorf("invalid document %d of %s: %w", index, name, err)
+		}
+		entities = append(entities, documentEntities...)
+	}
+	return entities, nil
+}
+
+func newEntitiesFromNode(node *yaml.Node, file string) ([]Entity, error) {
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		node = node.Content[0]
+	}
+	if node.Kind == 0 || node.Tag == yaml.NodeTagNull {
+		return nil, nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected an object", node.Line)
+	}
+
+	// decode through json so values have the same types as json decoded manifests
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return nil, fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	var manifest map[string]interface{}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, fmt.Errorf("line %d: %w", node.Line, err)
+	}
+
+	if kind, _ := manifest["kind"].(string); strings.HasSuffix(kind, listKindSuffix) {
+		if items := mappingValue(node, "items"); items != nil && items.Kind == yaml.SequenceNode {
+			var entities []Entity
+			for _, item := range items.Content {
+				itemEntities, err := newEntitiesFromNode(item, file)
+				if err != nil {
+					return nil, err
+				}
+				entities = append(entities, itemEntities...)
+			}
+			return entities, nil
+		}
+	}
+
+	entity, err := NewEntityFromManifest(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	entity.Source = &EntitySource{File: file, Line: node.Line}
+	return []Entity{entity}, nil
+}
+
+// mappingValue returns the value node of a key of a mapping node, nil if it does not exist
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
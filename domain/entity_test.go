@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEntitiesFromFile(t *testing.T) {
+	entities, err := NewEntitiesFromFile("testData/multi-entities.yaml")
+	assert.Nil(t, err)
+	assert.Len(t, entities, 4)
+
+	expected := []struct {
+		kind string
+		name string
+		line int
+	}{
+		{kind: "Namespace", name: "app", line: 2},
+		{kind: "ConfigMap", name: "config-1", line: 11},
+		{kind: "ConfigMap", name: "config-2", line: 16},
+		{kind: "Deployment", name: "app-1", line: 22},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.kind, entities[i].Kind)
+		assert.Equal(t, e.name, entities[i].Name)
+		assert.Equal(t, &EntitySource{File: "testData/multi-entities.yaml", Line: e.line}, entities[i].Source)
+	}
+	assert.Equal(t, "app", entities[3].Namespace)
+	assert.Equal(t, float64(2), entities[3].Manifest["spec"].(map[string]interface{})["replicas"])
+
+	_, err = NewEntitiesFromFile("testData/missing.yaml")
+	assert.Error(t, err)
+}
+
+func TestNewEntitiesFromYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		entities int
+		err      string
+	}{
+		{name: "empty", raw: ""},
+		{name: "comments only", raw: "# nothing here\n---\n"},
+		{name: "json", raw: `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "pod"}}`, entities: 1},
+		{name: "empty list", raw: "apiVersion: v1\nkind: List\nitems: []\n"},
+		{
+			name: "missing metadata",
+			raw:  "apiVersion: v1\nkind: Pod\n---\napiVersion: v1\nkind: Pod\nmetadata: {}\n",
+			err:  "invalid document 0 of input: line 1: metadata is missing or is not an object",
+		},
+		{
+			name: "metadata is not an object",
+			raw:  "apiVersion: v1\nkind: Pod\nmetadata: pod\n",
+			err:  "metadata is missing or is not an object",
+		},
+		{
+			name: "missing kind",
+			raw:  "apiVersion: v1\nmetadata:\n  name: pod\n",
+			err:  "kind is missing",
+		},
+		{
+			name: "invalid list item",
+			raw:  "apiVersion: v1\nkind: List\nitems:\n  - apiVersion: v1\n    kind: Pod\n",
+			err:  "line 4: metadata is missing",
+		},
+		{name: "not an object", raw: "- apiVersion: v1\n", err: "line 1: expected an object"},
+		{name: "invalid yaml", raw: "apiVersion: v1\nkind: [Pod\n", err: "failed to parse document 0 of input"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entities, err := NewEntitiesFromYAML([]byte(tt.raw), "")
+			if tt.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Len(t, entities, tt.entities)
+		})
+	}
+}
+
+func TestNewEntityFromSpec(t *testing.T) {
+	assert.NotPanics(t, func() {
+		entity := NewEntityFromSpec(map[string]interface{}{"apiVersion": "v1", "kind": "Pod"})
+		assert.Equal(t, "Pod", entity.Kind)
+	})
+	assert.NotPanics(t, func() {
+		NewEntityFromSpec(map[string]interface{}{"metadata": "pod"})
+	})
+
+	_, err := NewEntityFromManifest(nil)
+	assert.Error(t, err)
+	entity, err := NewEntityFromManifest(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name":          "pod",
+			"managedFields": []interface{}{},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "pod", entity.Name)
+	assert.NotContains(t, entity.Manifest["metadata"], "managedFields")
+}
@@ -9,8 +9,22 @@ type PoliciesSource interface {
 	GetPolicyConfig(ctx context.Context, entity Entity) (*PolicyConfig, error)
 }
 
+// PolicyConfigsSource is implemented by policies sources able to return multiple configs for an entity,
+// validators then keep the configs matching the entity and resolve them by precedence instead of using GetPolicyConfig
+type PolicyConfigsSource interface {
+	// GetPolicyConfigs returns the candidate policy configs of the entity, configs not matching it are ignored
+	GetPolicyConfigs(ctx context.Context, entity Entity) ([]PolicyConfig, error)
+}
+
 // PolicyValidationSink acts as a sink to send the results of a validation to
 type PolicyValidationSink interface {
 	// Write saves the results
 	Write(ctx context.Context, PolicyValidations []PolicyValidation) error
 }
+
+// NamedSink is implemented by sinks identifying themselves in delivery statuses and metrics,
+// sinks not implementing it are identified by their type name
+type NamedSink interface {
+	// Name returns the name of the sink
+	Name() string
+}
@@ -4,6 +4,24 @@ import (
 	v1 "k8s.io/api/core/v1"
 )
 
+const (
+	PolicySeverityLow      = "low"
+	PolicySeverityMedium   = "medium"
+	PolicySeverityHigh     = "high"
+	PolicySeverityCritical = "critical"
+
+	PolicyModeAdmission   = "admission"
+	PolicyModeAudit       = "audit"
+	PolicyModeTFAdmission = "tf-admission"
+)
+
+var (
+	// PolicySeverities are the allowed policy severities
+	PolicySeverities = []string{PolicySeverityLow, PolicySeverityMedium, PolicySeverityHigh, PolicySeverityCritical}
+	// PolicyModes are the allowed policy modes
+	PolicyModes = []string{PolicyModeAdmission, PolicyModeAudit, PolicyModeTFAdmission}
+)
+
 // PolicyTargets is used to match entities with the required fields specified by the policy
 type PolicyTargets struct {
 	Kinds      []string            `json:"kinds"`
@@ -18,6 +36,9 @@ type PolicyParameters struct {
 	Value     interface{} `json:"value"`
 	Required  bool        `json:"required"`
 	ConfigRef string      `json:"config_ref,omitempty"`
+	// Schema optionally describes the parameter value as a JSON Schema, values are validated
+	// against it after being coerced to Type
+	Schema map[string]interface{} `json:"schema,omitempty"`
 }
 
 type PolicyStandard struct {
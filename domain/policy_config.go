@@ -1,5 +1,38 @@
 package domain
 
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// PolicyConfigLevel is the scope a policy config applies to, configs of higher levels take precedence
+type PolicyConfigLevel int
+
+const (
+	PolicyConfigLevelCluster PolicyConfigLevel = iota
+	PolicyConfigLevelNamespace
+	PolicyConfigLevelApplication
+	PolicyConfigLevelResource
+)
+
+func (l PolicyConfigLevel) String() string {
+	switch l {
+	case PolicyConfigLevelCluster:
+		return "cluster"
+	case PolicyConfigLevelNamespace:
+		return "namespace"
+	case PolicyConfigLevelApplication:
+		return "app"
+	case PolicyConfigLevelResource:
+		return "resource"
+	}
+	return fmt.Sprintf("PolicyConfigLevel(%d)", int(l))
+}
+
 type ConfigMatchApplication struct {
 	Kind      string `json:"kind"`
 	Name      string `json:"name"`
@@ -12,12 +45,63 @@ type ConfigMatchResource struct {
 	Namespace string `json:"namespace"`
 }
 
+// PolicyConfigMatch selects the entities a policy config applies to, names, namespaces and kinds accept glob
+// patterns, e.g. team-*, and empty fields match any value. An empty match applies to all entities.
 type PolicyConfigMatch struct {
 	Namespaces   []string                 `json:"namespaces,omitempty"`
 	Applications []ConfigMatchApplication `json:"apps,omitempty"`
 	Resources    []ConfigMatchResource    `json:"resources,omitempty"`
 }
 
+// Match checks if the entity is in any of the namespaces, is owned by any of the applications,
+// according to its owner references, or is any of the resources
+func (m *PolicyConfigMatch) Match(entity Entity) bool {
+	_, ok := m.MatchLevel(entity)
+	return ok
+}
+
+// MatchLevel checks if the match selects the entity and returns the level of the most specific clause
+// selecting it, e.g. a match of namespaces and resources selecting the entity only by its namespace
+// returns the namespace level. An empty match selects all entities at the cluster level.
+func (m *PolicyConfigMatch) MatchLevel(entity Entity) (PolicyConfigLevel, bool) {
+	if len(m.Namespaces) == 0 && len(m.Applications) == 0 && len(m.Resources) == 0 {
+		return PolicyConfigLevelCluster, true
+	}
+
+	for _, resource := range m.Resources {
+		if matchOptional(resource.Kind, entity.Kind) &&
+			matchOptional(resource.Name, entity.Name) &&
+			matchOptional(resource.Namespace, entity.Namespace) {
+			return PolicyConfigLevelResource, true
+		}
+	}
+
+	if len(m.Applications) > 0 {
+		owners := (&unstructured.Unstructured{Object: entity.Manifest}).GetOwnerReferences()
+		for _, app := range m.Applications {
+			if !matchOptional(app.Namespace, entity.Namespace) {
+				continue
+			}
+			for _, owner := range owners {
+				if matchOptional(app.Kind, owner.Kind) && matchOptional(app.Name, owner.Name) {
+					return PolicyConfigLevelApplication, true
+				}
+			}
+		}
+	}
+
+	if matchAny(m.Namespaces, entity.Namespace) {
+		return PolicyConfigLevelNamespace, true
+	}
+
+	return PolicyConfigLevelCluster, false
+}
+
+// matchOptional matches the value against the pattern, an empty pattern matches any value
+func matchOptional(pattern, value string) bool {
+	return pattern == "" || matchPattern(pattern, value)
+}
+
 type PolicyConfigParameter struct {
 	Value     interface{}
 	ConfigRef string
@@ -29,6 +113,156 @@ type PolicyConfigConfig struct {
 
 // PolicyConfig represents a policy config
 type PolicyConfig struct {
+	// ID identifies the config, it is used as the parameters ConfigRef when they have none
+	ID     string                        `json:"id,omitempty"`
 	Config map[string]PolicyConfigConfig `json:"config"`
 	Match  PolicyConfigMatch             `json:"match"`
 }
+
+// AppliesTo checks if the config applies to the entity
+func (c *PolicyConfig) AppliesTo(entity Entity) bool {
+	return c.Match.Match(entity)
+}
+
+// MatchPolicyConfigs returns the configs applying to the entity
+func MatchPolicyConfigs(configs []PolicyConfig, entity Entity) []PolicyConfig {
+	var matched []PolicyConfig
+	for i := range configs {
+		if configs[i].AppliesTo(entity) {
+			matched = append(matched, configs[i])
+		}
+	}
+	return matched
+}
+
+// Level returns the level of the config, given by its most specific match, a config without match targets the cluster.
+// Use MatchLevel to get the level the config applies to a given entity at.
+func (c *PolicyConfig) Level() PolicyConfigLevel {
+	switch {
+	case len(c.Match.Resources) > 0:
+		return PolicyConfigLevelResource
+	case len(c.Match.Applications) > 0:
+		return PolicyConfigLevelApplication
+	case len(c.Match.Namespaces) > 0:
+		return PolicyConfigLevelNamespace
+	}
+	return PolicyConfigLevelCluster
+}
+
+// MatchLevel returns the level the config applies to the entity at and whether it applies to it
+func (c *PolicyConfig) MatchLevel(entity Entity) (PolicyConfigLevel, bool) {
+	return c.Match.MatchLevel(entity)
+}
+
+// PolicyConfigConflict describes configs of the same level setting different values to a policy parameter
+type PolicyConfigConflict struct {
+	PolicyID  string
+	Parameter string
+	Level     PolicyConfigLevel
+	// ConfigRefs are the refs of the conflicting configs, the first one is applied
+	ConfigRefs []string
+}
+
+func (c PolicyConfigConflict) Error() string {
+	return fmt.Sprintf(
+		"parameter %s of policy %s is set by multiple %s configs: %s",
+		c.Parameter,
+		c.PolicyID,
+		c.Level,
+		strings.Join(c.ConfigRefs, ", "),
+	)
+}
+
+// ResolvePolicyConfigs merges the configs applying to an entity into a single config.
+// Each parameter takes its value from the config of the highest level setting it, resource > app > namespace > cluster,
+// and records that config ref. Configs of the same level are applied in order of their ids and the parameters they
+// set to different values are returned as conflicts.
+func ResolvePolicyConfigs(configs []PolicyConfig) (*PolicyConfig, []PolicyConfigConflict) {
+	leveled := make([]leveledPolicyConfig, len(configs))
+	for i := range configs {
+		leveled[i] = leveledPolicyConfig{PolicyConfig: configs[i], level: configs[i].Level()}
+	}
+	return resolvePolicyConfigs(leveled)
+}
+
+// ResolveEntityPolicyConfigs merges the configs applying to the entity like ResolvePolicyConfigs,
+// the precedence of each config is the level it matches the entity at rather than its most specific match
+func ResolveEntityPolicyConfigs(configs []PolicyConfig, entity Entity) (*PolicyConfig, []PolicyConfigConflict) {
+	var leveled []leveledPolicyConfig
+	for i := range configs {
+		if level, ok := configs[i].MatchLevel(entity); ok {
+			leveled = append(leveled, leveledPolicyConfig{PolicyConfig: configs[i], level: level})
+		}
+	}
+	return resolvePolicyConfigs(leveled)
+}
+
+type leveledPolicyConfig struct {
+	PolicyConfig
+	level PolicyConfigLevel
+}
+
+func resolvePolicyConfigs(configs []leveledPolicyConfig) (*PolicyConfig, []PolicyConfigConflict) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+
+	sorted := append([]leveledPolicyConfig(nil), configs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if li, lj := sorted[i].level, sorted[j].level; li != lj {
+			return li > lj
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	type applied struct {
+		level PolicyConfigLevel
+		value interface{}
+		// conflict indexes the conflict of the parameter in conflicts, -1 when there is none
+		conflict int
+	}
+	resolved := &PolicyConfig{Config: map[string]PolicyConfigConfig{}}
+	appliedParams := map[string]map[string]*applied{}
+	var conflicts []PolicyConfigConflict
+
+	for _, config := range sorted {
+		level := config.level
+		policyIDs := make([]string, 0, len(config.Config))
+		for policyID := range config.Config {
+			policyIDs = append(policyIDs, policyID)
+		}
+		sort.Strings(policyIDs)
+
+		for _, policyID := range policyIDs {
+			if _, ok := resolved.Config[policyID]; !ok {
+				resolved.Config[policyID] = PolicyConfigConfig{Parameters: map[string]PolicyConfigParameter{}}
+				appliedParams[policyID] = map[string]*applied{}
+			}
+			for name, param := range config.Config[policyID].Parameters {
+				if param.ConfigRef == "" {
+					param.ConfigRef = config.ID
+				}
+				current, ok := appliedParams[policyID][name]
+				if !ok {
+					resolved.Config[policyID].Parameters[name] = param
+					appliedParams[policyID][name] = &applied{level: level, value: param.Value, conflict: -1}
+					continue
+				}
+				if current.level != level || reflect.DeepEqual(current.value, param.Value) {
+					continue
+				}
+				if current.conflict < 0 {
+					current.conflict = len(conflicts)
+					conflicts = append(conflicts, PolicyConfigConflict{
+						PolicyID:   policyID,
+						Parameter:  name,
+						Level:      level,
+						ConfigRefs: []string{resolved.Config[policyID].Parameters[name].ConfigRef},
+					})
+				}
+				conflicts[current.conflict].ConfigRefs = append(conflicts[current.conflict].ConfigRefs, param.ConfigRef)
+			}
+		}
+	}
+	return resolved, conflicts
+}
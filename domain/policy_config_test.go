@@ -0,0 +1,235 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyConfig_Level(t *testing.T) {
+	assert.Equal(t, PolicyConfigLevelCluster, (&PolicyConfig{}).Level())
+	assert.Equal(t, PolicyConfigLevelNamespace, (&PolicyConfig{Match: PolicyConfigMatch{
+		Namespaces: []string{"default"},
+	}}).Level())
+	assert.Equal(t, PolicyConfigLevelApplication, (&PolicyConfig{Match: PolicyConfigMatch{
+		Namespaces:   []string{"default"},
+		Applications: []ConfigMatchApplication{{Kind: "HelmRelease", Name: "app"}},
+	}}).Level())
+	assert.Equal(t, PolicyConfigLevelResource, (&PolicyConfig{Match: PolicyConfigMatch{
+		Resources: []ConfigMatchResource{{Kind: "Deployment", Name: "app"}},
+	}}).Level())
+	assert.Equal(t, "app", PolicyConfigLevelApplication.String())
+}
+
+func TestResolvePolicyConfigs(t *testing.T) {
+	config := func(id string, match PolicyConfigMatch, params map[string]interface{}) PolicyConfig {
+		parameters := map[string]PolicyConfigParameter{}
+		for name, value := range params {
+			parameters[name] = PolicyConfigParameter{Value: value}
+		}
+		return PolicyConfig{
+			ID:     id,
+			Match:  match,
+			Config: map[string]PolicyConfigConfig{"policy-1": {Parameters: parameters}},
+		}
+	}
+	namespace := PolicyConfigMatch{Namespaces: []string{"default"}}
+	app := PolicyConfigMatch{Applications: []ConfigMatchApplication{{Kind: "Kustomization", Name: "app"}}}
+	resource := PolicyConfigMatch{Resources: []ConfigMatchResource{{Kind: "Deployment", Name: "app"}}}
+
+	resolved, conflicts := ResolvePolicyConfigs(nil)
+	assert.Nil(t, resolved)
+	assert.Nil(t, conflicts)
+
+	resolved, conflicts = ResolvePolicyConfigs([]PolicyConfig{
+		config("cluster", PolicyConfigMatch{}, map[string]interface{}{"replicas": 1, "owner": "ops", "tag": "latest"}),
+		config("resource", resource, map[string]interface{}{"replicas": 4}),
+		config("namespace-b", namespace, map[string]interface{}{"replicas": 2, "owner": "dev", "tag": "v1"}),
+		config("app", app, map[string]interface{}{"replicas": 3}),
+		config("namespace-a", namespace, map[string]interface{}{"owner": "qa", "tag": "v1"}),
+		config("namespace-c", namespace, map[string]interface{}{"owner": "sre"}),
+	})
+	assert.Equal(t, map[string]PolicyConfigParameter{
+		"replicas": {Value: 4, ConfigRef: "resource"},
+		"owner":    {Value: "qa", ConfigRef: "namespace-a"},
+		"tag":      {Value: "v1", ConfigRef: "namespace-a"},
+	}, resolved.Config["policy-1"].Parameters)
+	assert.Equal(t, []PolicyConfigConflict{{
+		PolicyID:   "policy-1",
+		Parameter:  "owner",
+		Level:      PolicyConfigLevelNamespace,
+		ConfigRefs: []string{"namespace-a", "namespace-b", "namespace-c"},
+	}}, conflicts)
+	assert.EqualError(t, conflicts[0], "parameter owner of policy policy-1 is set by multiple namespace configs: namespace-a, namespace-b, namespace-c")
+
+	// explicit parameter refs are kept
+	withRef := config("cluster", PolicyConfigMatch{}, nil)
+	withRef.Config["policy-2"] = PolicyConfigConfig{Parameters: map[string]PolicyConfigParameter{
+		"owner": {Value: "ops", ConfigRef: "my-ref"},
+	}}
+	resolved, conflicts = ResolvePolicyConfigs([]PolicyConfig{withRef})
+	assert.Empty(t, conflicts)
+	assert.Equal(t, "my-ref", resolved.Config["policy-2"].Parameters["owner"].ConfigRef)
+}
+
+func TestPolicyConfigMatch_Match(t *testing.T) {
+	entity := NewEntityFromSpec(map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":      "payments-api",
+			"namespace": "team-payments",
+			"ownerReferences": []interface{}{
+				map[string]interface{}{
+					"apiVersion": "helm.toolkit.fluxcd.io/v2beta1",
+					"kind":       "HelmRelease",
+					"name":       "payments",
+					"uid":        "1234",
+				},
+			},
+		},
+	})
+
+	tests := []struct {
+		name  string
+		match PolicyConfigMatch
+		want  bool
+	}{
+		{name: "empty match", want: true},
+		{name: "namespace", match: PolicyConfigMatch{Namespaces: []string{"default", "team-payments"}}, want: true},
+		{name: "namespace wildcard", match: PolicyConfigMatch{Namespaces: []string{"team-*"}}, want: true},
+		{name: "other namespace", match: PolicyConfigMatch{Namespaces: []string{"default"}}},
+		{
+			name: "resource",
+			match: PolicyConfigMatch{Resources: []ConfigMatchResource{
+				{Kind: "Deployment", Name: "payments-api", Namespace: "team-payments"},
+			}},
+			want: true,
+		},
+		{
+			name:  "resource wildcard name",
+			match: PolicyConfigMatch{Resources: []ConfigMatchResource{{Kind: "Deployment", Name: "payments-*"}}},
+			want:  true,
+		},
+		{
+			name:  "resource of another kind",
+			match: PolicyConfigMatch{Resources: []ConfigMatchResource{{Kind: "StatefulSet", Name: "payments-api"}}},
+		},
+		{
+			name:  "resource in another namespace",
+			match: PolicyConfigMatch{Resources: []ConfigMatchResource{{Name: "payments-api", Namespace: "default"}}},
+		},
+		{
+			name: "owner app",
+			match: PolicyConfigMatch{Applications: []ConfigMatchApplication{
+				{Kind: "Kustomization", Name: "payments"},
+				{Kind: "HelmRelease", Name: "payments", Namespace: "team-payments"},
+			}},
+			want: true,
+		},
+		{
+			name:  "owner app wildcard name",
+			match: PolicyConfigMatch{Applications: []ConfigMatchApplication{{Kind: "HelmRelease", Name: "*"}}},
+			want:  true,
+		},
+		{
+			name:  "app not owning the entity",
+			match: PolicyConfigMatch{Applications: []ConfigMatchApplication{{Kind: "HelmRelease", Name: "orders"}}},
+		},
+		{
+			name: "app in another namespace",
+			match: PolicyConfigMatch{Applications: []ConfigMatchApplication{
+				{Kind: "HelmRelease", Name: "payments", Namespace: "default"},
+			}},
+		},
+		{
+			name: "any of the match kinds",
+			match: PolicyConfigMatch{
+				Namespaces: []string{"default"},
+				Resources:  []ConfigMatchResource{{Kind: "Deployment", Name: "payments-api"}},
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.match.Match(entity))
+		})
+	}
+
+	configs := []PolicyConfig{
+		{ID: "cluster"},
+		{ID: "default", Match: PolicyConfigMatch{Namespaces: []string{"default"}}},
+		{ID: "team", Match: PolicyConfigMatch{Namespaces: []string{"team-*"}}},
+	}
+	matched := MatchPolicyConfigs(configs, entity)
+	assert.Len(t, matched, 2)
+	assert.Equal(t, "cluster", matched[0].ID)
+	assert.Equal(t, "team", matched[1].ID)
+}
+
+func TestResolveEntityPolicyConfigs(t *testing.T) {
+	deployment := func(name string) Entity {
+		return NewEntityFromSpec(map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": "prod",
+				"ownerReferences": []interface{}{
+					map[string]interface{}{
+						"apiVersion": "helm.toolkit.fluxcd.io/v2beta1",
+						"kind":       "HelmRelease",
+						"name":       name,
+						"uid":        "1234",
+					},
+				},
+			},
+		})
+	}
+	configs := []PolicyConfig{
+		{
+			ID: "mixed",
+			Match: PolicyConfigMatch{
+				Namespaces: []string{"prod"},
+				Resources:  []ConfigMatchResource{{Kind: "Deployment", Name: "api"}},
+			},
+			Config: map[string]PolicyConfigConfig{"policy-1": {Parameters: map[string]PolicyConfigParameter{
+				"replicas": {Value: 2},
+			}}},
+		},
+		{
+			ID:    "apps",
+			Match: PolicyConfigMatch{Applications: []ConfigMatchApplication{{Kind: "HelmRelease", Name: "*"}}},
+			Config: map[string]PolicyConfigConfig{"policy-1": {Parameters: map[string]PolicyConfigParameter{
+				"replicas": {Value: 3},
+			}}},
+		},
+		{
+			ID:    "staging",
+			Match: PolicyConfigMatch{Namespaces: []string{"staging"}},
+			Config: map[string]PolicyConfigConfig{"policy-1": {Parameters: map[string]PolicyConfigParameter{
+				"replicas": {Value: 1},
+			}}},
+		},
+	}
+
+	level, ok := configs[0].MatchLevel(deployment("web"))
+	assert.True(t, ok)
+	assert.Equal(t, PolicyConfigLevelNamespace, level)
+	level, ok = configs[0].MatchLevel(deployment("api"))
+	assert.True(t, ok)
+	assert.Equal(t, PolicyConfigLevelResource, level)
+	_, ok = configs[2].MatchLevel(deployment("web"))
+	assert.False(t, ok)
+
+	// the mixed config only matches web by its namespace, so the app config takes precedence
+	resolved, conflicts := ResolveEntityPolicyConfigs(configs, deployment("web"))
+	assert.Empty(t, conflicts)
+	assert.Equal(t, PolicyConfigParameter{Value: 3, ConfigRef: "apps"}, resolved.Config["policy-1"].Parameters["replicas"])
+
+	resolved, conflicts = ResolveEntityPolicyConfigs(configs, deployment("api"))
+	assert.Empty(t, conflicts)
+	assert.Equal(t, PolicyConfigParameter{Value: 2, ConfigRef: "mixed"}, resolved.Config["policy-1"].Parameters["replicas"])
+}
@@ -0,0 +1,284 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+const (
+	PolicyParameterTypeString  = "string"
+	PolicyParameterTypeInteger = "integer"
+	PolicyParameterTypeNumber  = "number"
+	PolicyParameterTypeBoolean = "boolean"
+	PolicyParameterTypeArray   = "array"
+	PolicyParameterTypeObject  = "object"
+)
+
+// CoerceValue checks the value against the parameter type and converts compatible values to it,
+// e.g. string "3" to integer 3. Nil values and parameters of unknown types are returned as is.
+func (p *PolicyParameters) CoerceValue(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	if number, ok := value.(json.Number); ok {
+		if i, err := number.Int64(); err == nil {
+			value = i
+		} else if f, err := number.Float64(); err == nil {
+			value = f
+		}
+	}
+
+	kind := reflect.ValueOf(value).Kind()
+	switch strings.ToLower(p.Type) {
+	case PolicyParameterTypeString:
+		switch {
+		case kind == reflect.String:
+			return value, nil
+		case kind == reflect.Bool || isNumberKind(kind):
+			return fmt.Sprint(value), nil
+		}
+	case PolicyParameterTypeInteger:
+		switch {
+		case isIntegerKind(kind):
+			return value, nil
+		case kind == reflect.Float32 || kind == reflect.Float64:
+			f := reflect.ValueOf(value).Float()
+			if f == math.Trunc(f) {
+				return value, nil
+			}
+		case kind == reflect.String:
+			if i, err := strconv.ParseInt(strings.TrimSpace(value.(string)), 10, 64); err == nil {
+				return i, nil
+			}
+		}
+	case PolicyParameterTypeNumber:
+		switch {
+		case isNumberKind(kind):
+			return value, nil
+		case kind == reflect.String:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(value.(string)), 64); err == nil {
+				return f, nil
+			}
+		}
+	case PolicyParameterTypeBoolean:
+		switch kind {
+		case reflect.Bool:
+			return value, nil
+		case reflect.String:
+			if b, err := strconv.ParseBool(strings.TrimSpace(value.(string))); err == nil {
+				return b, nil
+			}
+		}
+	case PolicyParameterTypeArray:
+		if kind == reflect.Slice || kind == reflect.Array {
+			return value, nil
+		}
+	case PolicyParameterTypeObject:
+		if kind == reflect.Map || kind == reflect.Struct {
+			return value, nil
+		}
+	default:
+		return value, nil
+	}
+	return nil, fmt.Errorf("parameter %s expects a value of type %s, found %T %v", p.Name, p.Type, value, value)
+}
+
+// ValidateParameters checks the given parameters values against the policy parameters definitions,
+// it returns the parameters with values coerced to their types or an error listing every invalid parameter.
+// Required parameters without a value, absent or nil, are reported as missing
+func (p *Policy) ValidateParameters(parameters map[string]interface{}) (map[string]interface{}, error) {
+	validated := make(map[string]interface{}, len(parameters))
+	for name, value := range parameters {
+		validated[name] = value
+	}
+
+	var errs error
+	for i := range p.Parameters {
+		param := &p.Parameters[i]
+		value := validated[param.Name]
+		if value == nil {
+			if param.Required {
+				errs = multierror.Append(errs, fmt.Errorf("parameter %s is required", param.Name))
+			}
+			continue
+		}
+		coerced, err := param.validateValue(value)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		validated[param.Name] = coerced
+	}
+	if errs != nil {
+		return nil, fmt.Errorf("invalid parameters of policy %s: %w", p.ID, errs)
+	}
+	return validated, nil
+}
+
+// ValidateParameterDefaults checks the default values of the policy parameters against their types and schemas
+func (p *Policy) ValidateParameterDefaults() error {
+	var errs error
+	for i := range p.Parameters {
+		if p.Parameters[i].Value == nil {
+			continue
+		}
+		if _, err := p.Parameters[i].validateValue(p.Parameters[i].Value); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("invalid parameter defaults of policy %s: %w", p.ID, errs)
+	}
+	return nil
+}
+
+// ValidateConfig checks the parameters overridden by a policy config against the policy parameters definitions
+func (p *Policy) ValidateConfig(config PolicyConfigConfig) error {
+	var errs error
+	for name, configParam := range config.Parameters {
+		param := p.getParameter(name)
+		if param == nil {
+			errs = multierror.Append(errs, fmt.Errorf("unknown parameter %s", name))
+			continue
+		}
+		if configParam.Value == nil {
+			continue
+		}
+		if _, err := param.validateValue(configParam.Value); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("invalid config of policy %s: %w", p.ID, errs)
+	}
+	return nil
+}
+
+// ParametersSchema returns a JSON Schema of an object holding all the policy parameters,
+// parameters default values are set as the schema defaults
+func (p *Policy) ParametersSchema() map[string]interface{} {
+	properties := make(map[string]interface{}, len(p.Parameters))
+	var required []string
+	for i := range p.Parameters {
+		param := &p.Parameters[i]
+		schema := make(map[string]interface{})
+		for key, value := range param.JSONSchema() {
+			schema[key] = value
+		}
+		if _, ok := schema["default"]; !ok && param.Value != nil {
+			schema["default"] = param.Value
+		}
+		properties[param.Name] = schema
+		if param.Required {
+			required = append(required, param.Name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"$schema":              jsonSchemaDraft,
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// JSONSchema returns the JSON Schema of the parameter value, derived from Type when Schema is not set
+func (p *PolicyParameters) JSONSchema() map[string]interface{} {
+	if p.Schema != nil {
+		return p.Schema
+	}
+	switch typ := strings.ToLower(p.Type); typ {
+	case PolicyParameterTypeString,
+		PolicyParameterTypeInteger,
+		PolicyParameterTypeNumber,
+		PolicyParameterTypeBoolean,
+		PolicyParameterTypeArray,
+		PolicyParameterTypeObject:
+		return map[string]interface{}{"type": typ}
+	}
+	return map[string]interface{}{}
+}
+
+// validateValue coerces the value to the parameter type and validates it against the parameter schema
+func (p *PolicyParameters) validateValue(value interface{}) (interface{}, error) {
+	coerced, err := p.CoerceValue(value)
+	if err != nil {
+		return nil, err
+	}
+	if p.Schema == nil || coerced == nil {
+		return coerced, nil
+	}
+
+	schema, err := compileSchema(p.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema of parameter %s: %w", p.Name, err)
+	}
+	result, err := schema.Validate(gojsonschema.NewGoLoader(coerced))
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate parameter %s against its schema: %w", p.Name, err)
+	}
+	if !result.Valid() {
+		var messages []string
+		for _, resultErr := range result.Errors() {
+			messages = append(messages, resultErr.String())
+		}
+		return nil, fmt.Errorf("parameter %s does not match its schema: %s", p.Name, strings.Join(messages, "; "))
+	}
+	return coerced, nil
+}
+
+func (p *Policy) getParameter(name string) *PolicyParameters {
+	for i := range p.Parameters {
+		if p.Parameters[i].Name == name {
+			return &p.Parameters[i]
+		}
+	}
+	return nil
+}
+
+// compiledSchemas caches compiled schemas by their json representation
+var compiledSchemas sync.Map
+
+func compileSchema(schema map[string]interface{}) (*gojsonschema.Schema, error) {
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	if compiled, ok := compiledSchemas.Load(string(raw)); ok {
+		return compiled.(*gojsonschema.Schema), nil
+	}
+	compiled, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(raw))
+	if err != nil {
+		return nil, err
+	}
+	compiledSchemas.Store(string(raw), compiled)
+	return compiled, nil
+}
+
+func isIntegerKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	return isIntegerKind(kind) || kind == reflect.Float32 || kind == reflect.Float64
+}
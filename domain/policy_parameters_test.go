@@ -0,0 +1,164 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyParameters_CoerceValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     string
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "nil", typ: PolicyParameterTypeInteger, value: nil, want: nil},
+		{name: "string", typ: PolicyParameterTypeString, value: "latest", want: "latest"},
+		{name: "number to string", typ: PolicyParameterTypeString, value: 3, want: "3"},
+		{name: "array to string", typ: PolicyParameterTypeString, value: []string{"a"}, wantErr: true},
+		{name: "integer", typ: PolicyParameterTypeInteger, value: 3, want: 3},
+		{name: "integral float to integer", typ: PolicyParameterTypeInteger, value: 3.0, want: 3.0},
+		{name: "fraction to integer", typ: PolicyParameterTypeInteger, value: 3.5, wantErr: true},
+		{name: "string to integer", typ: PolicyParameterTypeInteger, value: "3", want: int64(3)},
+		{name: "json number to integer", typ: PolicyParameterTypeInteger, value: json.Number("3"), want: int64(3)},
+		{name: "invalid string to integer", typ: PolicyParameterTypeInteger, value: "three", wantErr: true},
+		{name: "string to number", typ: PolicyParameterTypeNumber, value: "0.5", want: 0.5},
+		{name: "boolean", typ: PolicyParameterTypeBoolean, value: true, want: true},
+		{name: "string to boolean", typ: PolicyParameterTypeBoolean, value: "false", want: false},
+		{name: "invalid boolean", typ: PolicyParameterTypeBoolean, value: 1, wantErr: true},
+		{name: "array", typ: PolicyParameterTypeArray, value: []interface{}{"kube-system"}, want: []interface{}{"kube-system"}},
+		{name: "string to array", typ: PolicyParameterTypeArray, value: "kube-system", wantErr: true},
+		{name: "object", typ: PolicyParameterTypeObject, value: map[string]interface{}{}, want: map[string]interface{}{}},
+		{name: "string to object", typ: PolicyParameterTypeObject, value: "{}", wantErr: true},
+		{name: "unknown type", typ: "custom", value: "anything", want: "anything"},
+		{name: "case insensitive type", typ: "Integer", value: "3", want: int64(3)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			param := PolicyParameters{Name: "param", Type: tt.typ}
+			got, err := param.CoerceValue(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPolicy_ValidateParameters(t *testing.T) {
+	policy := Policy{
+		ID: "my-policy",
+		Parameters: []PolicyParameters{
+			{Name: "replica_count", Type: PolicyParameterTypeInteger, Required: true},
+			{Name: "exclude_namespaces", Type: PolicyParameterTypeArray, Required: true},
+			{Name: "exclude_label_key", Type: PolicyParameterTypeString},
+		},
+	}
+
+	got, err := policy.ValidateParameters(map[string]interface{}{
+		"replica_count":      "3",
+		"exclude_namespaces": []string{"kube-system"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), got["replica_count"])
+
+	_, err = policy.ValidateParameters(map[string]interface{}{
+		"replica_count":      3,
+		"exclude_namespaces": nil,
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "parameter exclude_namespaces is required")
+
+	_, err = policy.ValidateParameters(map[string]interface{}{
+		"replica_count":     "three",
+		"exclude_label_key": "owner",
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "my-policy")
+	assert.Contains(t, err.Error(), "parameter replica_count expects a value of type integer")
+	assert.Contains(t, err.Error(), "parameter exclude_namespaces is required")
+}
+
+func TestPolicy_ParametersSchema(t *testing.T) {
+	policy := Policy{
+		ID: "my-policy",
+		Parameters: []PolicyParameters{
+			{
+				Name:     "replica_count",
+				Type:     PolicyParameterTypeInteger,
+				Required: true,
+				Value:    2,
+				Schema:   map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 10},
+			},
+			{Name: "exclude_namespaces", Type: PolicyParameterTypeArray},
+			{Name: "exclude_label_key", Type: "String"},
+		},
+	}
+
+	assert.Nil(t, policy.ValidateParameterDefaults())
+
+	schema := policy.ParametersSchema()
+	assert.Equal(t, "object", schema["type"])
+	assert.Equal(t, []string{"replica_count"}, schema["required"])
+	properties := schema["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 10, "default": 2}, properties["replica_count"])
+	assert.Equal(t, map[string]interface{}{"type": "array"}, properties["exclude_namespaces"])
+	assert.Equal(t, map[string]interface{}{"type": "string"}, properties["exclude_label_key"])
+	assert.NotContains(t, policy.Parameters[0].Schema, "default")
+
+	_, err := policy.ValidateParameters(map[string]interface{}{"replica_count": "20"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "parameter replica_count does not match its schema")
+
+	got, err := policy.ValidateParameters(map[string]interface{}{"replica_count": "5"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), got["replica_count"])
+
+	policy.Parameters[0].Value = 0
+	assert.Error(t, policy.ValidateParameterDefaults())
+
+	policy.Parameters[1].Schema = map[string]interface{}{"type": 1}
+	_, err = policy.ValidateParameters(map[string]interface{}{"replica_count": 5, "exclude_namespaces": []string{}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid schema of parameter exclude_namespaces")
+}
+
+func TestPolicy_ValidateConfig(t *testing.T) {
+	policy := Policy{
+		ID: "my-policy",
+		Parameters: []PolicyParameters{
+			{
+				Name:   "exclude_namespaces",
+				Type:   PolicyParameterTypeArray,
+				Schema: map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			},
+			{Name: "replica_count", Type: PolicyParameterTypeInteger},
+		},
+	}
+
+	err := policy.ValidateConfig(PolicyConfigConfig{
+		Parameters: map[string]PolicyConfigParameter{
+			"exclude_namespaces": {Value: []interface{}{"kube-system"}},
+			"replica_count":      {Value: "3"},
+		},
+	})
+	assert.Nil(t, err)
+
+	err = policy.ValidateConfig(PolicyConfigConfig{
+		Parameters: map[string]PolicyConfigParameter{
+			"exclude_namespaces": {Value: []interface{}{1}},
+			"replica_count":      {Value: "three"},
+			"unknown":            {Value: true},
+		},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "parameter exclude_namespaces does not match its schema")
+	assert.Contains(t, err.Error(), "parameter replica_count expects a value of type integer")
+	assert.Contains(t, err.Error(), "unknown parameter unknown")
+}
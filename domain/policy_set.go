@@ -1,12 +1,43 @@
 package domain
 
-// PolicySetFilters defines a policy filters
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+const (
+	regexPatternPrefix = "regex:"
+	globMetaChars      = "*?[\\"
+)
+
+// PolicySetFilters defines a policy filters.
+// Filter values are glob patterns, e.g. weave.policies.containers.*, or regular expressions prefixed
+// with regex:, and standards are matched by standard id or by standardID/controlID.
+// A policy matching IDs is selected regardless of the other fields, otherwise it must match
+// any of Categories, Severities, Standards, Tags or Controls. Those flat fields are combined with the
+// And, Or and Not filters, which all must hold, and policies matching Exclude are never selected.
 type PolicySetFilters struct {
 	IDs        []string `json:"ids"`
 	Categories []string `json:"categories"`
 	Severities []string `json:"severities"`
 	Standards  []string `json:"standards"`
 	Tags       []string `json:"tags"`
+	// Controls matches policies having any of the controls in any of their standards
+	Controls []string `json:"controls,omitempty"`
+
+	// And matches policies matching all of its filters
+	And []PolicySetFilters `json:"and,omitempty"`
+	// Or matches policies matching any of its filters
+	Or []PolicySetFilters `json:"or,omitempty"`
+	// Not matches policies not matching its filter
+	Not *PolicySetFilters `json:"not,omitempty"`
+	// Exclude drops the policies matching its filter
+	Exclude *PolicySetFilters `json:"exclude,omitempty"`
 }
 
 // PolicySet represents a policy set
@@ -19,54 +50,189 @@ type PolicySet struct {
 
 // Match checks if the provided policy matches the policy set or not
 func (ps *PolicySet) Match(policy Policy) bool {
-	if len(ps.Filters.IDs) > 0 {
-		for _, id := range ps.Filters.IDs {
-			if policy.ID == id {
-				return true
-			}
-		}
+	return ps.Filters.Match(policy)
+}
+
+// Match checks if the provided policy matches the filters, empty filters match nothing
+func (f *PolicySetFilters) Match(policy Policy) bool {
+	if f.Exclude != nil && f.Exclude.Match(policy) {
 		return false
 	}
 
-	if len(ps.Filters.Categories) > 0 {
-		for _, category := range ps.Filters.Categories {
-			if policy.Category == category {
-				return true
-			}
+	var defined bool
+	if f.hasFlatFilters() {
+		defined = true
+		if !f.matchFlat(policy) {
+			return false
 		}
 	}
 
-	if len(ps.Filters.Severities) > 0 {
-		for _, severity := range ps.Filters.Severities {
-			if policy.Severity == severity {
-				return true
+	for i := range f.And {
+		defined = true
+		if !f.And[i].Match(policy) {
+			return false
+		}
+	}
+
+	if len(f.Or) > 0 {
+		defined = true
+		var matched bool
+		for i := range f.Or {
+			if f.Or[i].Match(policy) {
+				matched = true
+				break
 			}
 		}
+		if !matched {
+			return false
+		}
 	}
 
-	if len(ps.Filters.Standards) > 0 {
-		standards := map[string]struct{}{}
-		for _, standard := range ps.Filters.Standards {
-			standards[standard] = struct{}{}
+	if f.Not != nil {
+		defined = true
+		if f.Not.Match(policy) {
+			return false
 		}
+	}
+
+	return defined
+}
+
+func (f *PolicySetFilters) hasFlatFilters() bool {
+	return len(f.IDs) > 0 ||
+		len(f.Categories) > 0 ||
+		len(f.Severities) > 0 ||
+		len(f.Standards) > 0 ||
+		len(f.Tags) > 0 ||
+		len(f.Controls) > 0
+}
+
+// matchFlat matches the policy against the ids, categories, severities, standards, tags and controls filters
+func (f *PolicySetFilters) matchFlat(policy Policy) bool {
+	if len(f.IDs) > 0 {
+		return matchAny(f.IDs, policy.ID)
+	}
+
+	if matchAny(f.Categories, policy.Category) {
+		return true
+	}
+
+	if matchAny(f.Severities, policy.Severity) {
+		return true
+	}
+
+	if len(f.Standards) > 0 {
 		for _, standard := range policy.Standards {
-			if _, ok := standards[standard.ID]; ok {
+			if matchAny(f.Standards, standard.ID) {
 				return true
 			}
+			for _, control := range standard.Controls {
+				if matchAny(f.Standards, standard.ID+"/"+control) {
+					return true
+				}
+			}
 		}
 	}
 
-	if len(ps.Filters.Tags) > 0 {
-		tags := map[string]struct{}{}
-		for _, tag := range ps.Filters.Tags {
-			tags[tag] = struct{}{}
+	for _, tag := range policy.Tags {
+		if matchAny(f.Tags, tag) {
+			return true
 		}
-		for _, tag := range policy.Tags {
-			if _, ok := tags[tag]; ok {
-				return true
+	}
+
+	if len(f.Controls) > 0 {
+		for _, standard := range policy.Standards {
+			for _, control := range standard.Controls {
+				if matchAny(f.Controls, control) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// Validate checks that the filters patterns are valid
+func (f *PolicySetFilters) Validate() error {
+	var errs error
+	for _, patterns := range [][]string{f.IDs, f.Categories, f.Severities, f.Standards, f.Tags, f.Controls} {
+		for _, pattern := range patterns {
+			if err := validatePattern(pattern); err != nil {
+				errs = multierror.Append(errs, err)
 			}
 		}
 	}
+	for _, nested := range append(append([]PolicySetFilters(nil), f.And...), f.Or...) {
+		if err := nested.Validate(); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	for _, nested := range []*PolicySetFilters{f.Not, f.Exclude} {
+		if nested == nil {
+			continue
+		}
+		if err := nested.Validate(); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs
+}
 
+// matchAny checks if the value matches any of the patterns
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matchPattern(pattern, value) {
+			return true
+		}
+	}
 	return false
 }
+
+// matchPattern matches the value against a pattern, which is either a regular expression prefixed
+// with regex: that must match the whole value, a glob pattern as supported by path.Match or a literal value
+func matchPattern(pattern, value string) bool {
+	if strings.HasPrefix(pattern, regexPatternPrefix) {
+		re, err := compilePattern(pattern)
+		if err != nil {
+			return false
+		}
+		return re.MatchString(value)
+	}
+	if !strings.ContainsAny(pattern, globMetaChars) {
+		return pattern == value
+	}
+	matched, err := path.Match(pattern, value)
+	if err != nil {
+		return pattern == value
+	}
+	return matched
+}
+
+func validatePattern(pattern string) error {
+	if strings.HasPrefix(pattern, regexPatternPrefix) {
+		if _, err := compilePattern(pattern); err != nil {
+			return fmt.Errorf("invalid regex pattern %s: %w", pattern, err)
+		}
+		return nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid glob pattern %s: %w", pattern, err)
+	}
+	return nil
+}
+
+// compiledPatterns caches compiled regex patterns by pattern
+var compiledPatterns sync.Map
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := compiledPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile("^(?:" + strings.TrimPrefix(pattern, regexPatternPrefix) + ")$")
+	if err != nil {
+		return nil, err
+	}
+	compiledPatterns.Store(pattern, re)
+	return re, nil
+}
@@ -1,6 +1,11 @@
 package domain
 
-import "testing"
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
 
 func TestPolicySet(t *testing.T) {
 	tests := []struct {
@@ -81,3 +86,288 @@ func TestPolicySet(t *testing.T) {
 		}
 	}
 }
+
+func TestPolicySet_Composition(t *testing.T) {
+	pciHigh := Policy{
+		ID:        "pci-high",
+		Category:  "security",
+		Severity:  "high",
+		Standards: []PolicyStandard{{ID: "pci-dss"}},
+	}
+	pciLow := Policy{
+		ID:        "pci-low",
+		Category:  "security",
+		Severity:  "low",
+		Standards: []PolicyStandard{{ID: "pci-dss"}},
+	}
+	experimental := Policy{
+		ID:       "experimental",
+		Category: "security",
+		Severity: "high",
+		Tags:     []string{"experimental"},
+	}
+	reliability := Policy{
+		ID:       "reliability",
+		Category: "reliability",
+		Severity: "medium",
+	}
+	policies := []Policy{pciHigh, pciLow, experimental, reliability}
+
+	tests := []struct {
+		Name    string
+		Filters PolicySetFilters
+		Matches []string
+	}{
+		{
+			Name:    "empty",
+			Filters: PolicySetFilters{},
+		},
+		{
+			Name:    "flat fields are ored",
+			Filters: PolicySetFilters{Severities: []string{"low"}, Categories: []string{"reliability"}},
+			Matches: []string{"pci-low", "reliability"},
+		},
+		{
+			Name:    "ids are exclusive",
+			Filters: PolicySetFilters{IDs: []string{"pci-low"}, Severities: []string{"high"}},
+			Matches: []string{"pci-low"},
+		},
+		{
+			Name: "and",
+			Filters: PolicySetFilters{And: []PolicySetFilters{
+				{Severities: []string{"high"}},
+				{Standards: []string{"pci-dss"}},
+			}},
+			Matches: []string{"pci-high"},
+		},
+		{
+			Name: "or",
+			Filters: PolicySetFilters{Or: []PolicySetFilters{
+				{IDs: []string{"pci-low"}},
+				{Categories: []string{"reliability"}},
+			}},
+			Matches: []string{"pci-low", "reliability"},
+		},
+		{
+			Name:    "not",
+			Filters: PolicySetFilters{Not: &PolicySetFilters{Categories: []string{"security"}}},
+			Matches: []string{"reliability"},
+		},
+		{
+			Name: "flat fields and composition",
+			Filters: PolicySetFilters{
+				Categories: []string{"security"},
+				Not:        &PolicySetFilters{Severities: []string{"low"}},
+			},
+			Matches: []string{"pci-high", "experimental"},
+		},
+		{
+			Name: "exclude",
+			Filters: PolicySetFilters{
+				Categories: []string{"security"},
+				Exclude:    &PolicySetFilters{Tags: []string{"experimental"}},
+			},
+			Matches: []string{"pci-high", "pci-low"},
+		},
+		{
+			Name: "exclude takes precedence over ids",
+			Filters: PolicySetFilters{
+				IDs:     []string{"experimental", "reliability"},
+				Exclude: &PolicySetFilters{IDs: []string{"experimental"}},
+			},
+			Matches: []string{"reliability"},
+		},
+		{
+			Name:    "exclude only",
+			Filters: PolicySetFilters{Exclude: &PolicySetFilters{IDs: []string{"experimental"}}},
+		},
+		{
+			Name: "nested",
+			Filters: PolicySetFilters{Or: []PolicySetFilters{
+				{And: []PolicySetFilters{
+					{Severities: []string{"high"}},
+					{Not: &PolicySetFilters{Tags: []string{"experimental"}}},
+				}},
+				{Severities: []string{"medium"}},
+			}},
+			Matches: []string{"pci-high", "reliability"},
+		},
+		{
+			Name: "empty nested filter",
+			Filters: PolicySetFilters{
+				Categories: []string{"security"},
+				And:        []PolicySetFilters{{}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			set := PolicySet{Filters: test.Filters}
+			var matches []string
+			for _, policy := range policies {
+				if set.Match(policy) {
+					matches = append(matches, policy.ID)
+				}
+			}
+			if !reflect.DeepEqual(matches, test.Matches) {
+				t.Errorf("expected matches: %v but found: %v", test.Matches, matches)
+			}
+		})
+	}
+}
+
+func TestPolicySetFilters_JSON(t *testing.T) {
+	var set PolicySet
+	err := json.Unmarshal([]byte(`{
+		"id": "my-set",
+		"filters": {
+			"severities": ["high"],
+			"not": {"tags": ["experimental"]},
+			"exclude": {"ids": ["pci-low"]}
+		}
+	}`), &set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !set.Match(Policy{ID: "pci-high", Severity: "high"}) {
+		t.Error("expected high severity policy to match")
+	}
+	if set.Match(Policy{ID: "experimental", Severity: "high", Tags: []string{"experimental"}}) {
+		t.Error("expected experimental policy not to match")
+	}
+
+	raw, err := json.Marshal(PolicySetFilters{Tags: []string{"my-tag"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"ids":null,"categories":null,"severities":null,"standards":null,"tags":["my-tag"]}`
+	if string(raw) != expected {
+		t.Errorf("expected flat filters json: %s but found: %s", expected, raw)
+	}
+}
+
+func TestPolicySet_Patterns(t *testing.T) {
+	policies := []Policy{
+		{
+			ID:        "weave.policies.containers.image-tag",
+			Category:  "weave.categories.software-supply-chain",
+			Severity:  "high",
+			Tags:      []string{"pci-dss", "mitre-attack"},
+			Standards: []PolicyStandard{{ID: "weave.standards.cis-benchmark", Controls: []string{"5.2.1", "5.2.6"}}},
+		},
+		{
+			ID:        "weave.policies.containers.privileged",
+			Category:  "weave.categories.pod-security",
+			Severity:  "critical",
+			Standards: []PolicyStandard{{ID: "weave.standards.cis-benchmark", Controls: []string{"5.2.5"}}},
+		},
+		{
+			ID:        "weave.policies.rbac.cluster-admin",
+			Category:  "weave.categories.access-control",
+			Severity:  "medium",
+			Tags:      []string{"rbac"},
+			Standards: []PolicyStandard{{ID: "weave.standards.soc2-type-i", Controls: []string{"6.1"}}},
+		},
+	}
+
+	tests := []struct {
+		Name    string
+		Filters PolicySetFilters
+		Matches []string
+	}{
+		{
+			Name:    "ids glob",
+			Filters: PolicySetFilters{IDs: []string{"weave.policies.containers.*"}},
+			Matches: []string{"weave.policies.containers.image-tag", "weave.policies.containers.privileged"},
+		},
+		{
+			Name:    "categories glob",
+			Filters: PolicySetFilters{Categories: []string{"*.pod-security"}},
+			Matches: []string{"weave.policies.containers.privileged"},
+		},
+		{
+			Name:    "severities regex",
+			Filters: PolicySetFilters{Severities: []string{"regex:high|critical"}},
+			Matches: []string{"weave.policies.containers.image-tag", "weave.policies.containers.privileged"},
+		},
+		{
+			Name:    "regex matches the whole value",
+			Filters: PolicySetFilters{Severities: []string{"regex:high|crit"}},
+			Matches: []string{"weave.policies.containers.image-tag"},
+		},
+		{
+			Name:    "tags character class",
+			Filters: PolicySetFilters{Tags: []string{"[pr]*"}},
+			Matches: []string{"weave.policies.containers.image-tag", "weave.policies.rbac.cluster-admin"},
+		},
+		{
+			Name:    "standard id",
+			Filters: PolicySetFilters{Standards: []string{"weave.standards.cis-benchmark"}},
+			Matches: []string{"weave.policies.containers.image-tag", "weave.policies.containers.privileged"},
+		},
+		{
+			Name:    "standard control",
+			Filters: PolicySetFilters{Standards: []string{"weave.standards.cis-benchmark/5.2.5"}},
+			Matches: []string{"weave.policies.containers.privileged"},
+		},
+		{
+			Name:    "standard controls glob",
+			Filters: PolicySetFilters{Standards: []string{"*/5.2.*", "weave.standards.soc2-*/6.1"}},
+			Matches: []string{
+				"weave.policies.containers.image-tag",
+				"weave.policies.containers.privileged",
+				"weave.policies.rbac.cluster-admin",
+			},
+		},
+		{
+			Name:    "glob does not cross control separator",
+			Filters: PolicySetFilters{Standards: []string{"weave.standards.*5.2.5"}},
+		},
+		{
+			Name:    "invalid patterns match nothing",
+			Filters: PolicySetFilters{IDs: []string{"regex:(", "weave.policies.["}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			set := PolicySet{Filters: test.Filters}
+			var matches []string
+			for _, policy := range policies {
+				if set.Match(policy) {
+					matches = append(matches, policy.ID)
+				}
+			}
+			if !reflect.DeepEqual(matches, test.Matches) {
+				t.Errorf("expected matches: %v but found: %v", test.Matches, matches)
+			}
+		})
+	}
+}
+
+func TestPolicySetFilters_Validate(t *testing.T) {
+	valid := PolicySetFilters{
+		IDs:  []string{"weave.policies.*", "regex:weave\\.policies\\..+"},
+		Tags: []string{"[a-z]*"},
+		Not:  &PolicySetFilters{Tags: []string{"experimental"}},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	invalid := PolicySetFilters{
+		Tags:    []string{"[a-z"},
+		And:     []PolicySetFilters{{Categories: []string{"regex:("}}},
+		Exclude: &PolicySetFilters{IDs: []string{"regex:[z-a]"}},
+	}
+	err := invalid.Validate()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, pattern := range []string{"[a-z", "regex:(", "regex:[z-a]"} {
+		if !strings.Contains(err.Error(), pattern) {
+			t.Errorf("expected error to mention pattern %s, found: %v", pattern, err)
+		}
+	}
+}
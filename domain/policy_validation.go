@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	multierror "github.com/hashicorp/go-multierror"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -13,6 +14,7 @@ import (
 const (
 	PolicyValidationStatusViolating = "Violation"
 	PolicyValidationStatusCompliant = "Compliance"
+	PolicyValidationStatusResolved  = "Resolved"
 	EventActionAllowed              = "Allowed"
 	EventActionRejected             = "Rejected"
 	EventReasonPolicyViolation      = "PolicyViolation"
@@ -60,6 +62,18 @@ type PolicyValidation struct {
 	Trigger     string       `json:"trigger"`
 	CreatedAt   time.Time    `json:"created_at"`
 	Metadata    interface{}  `json:"metadata"`
+	// PolicySets are the ids of the policy sets that selected the policy
+	PolicySets []string `json:"policy_sets,omitempty"`
+}
+
+// SinkDeliveryStatus describes the outcome of writing validation results to a sink
+type SinkDeliveryStatus struct {
+	// Sink is the name of the sink when it implements NamedSink, its type name otherwise
+	Sink string
+	// Delivered is the number of results written successfully
+	Delivered int
+	// Error is the error returned by the sink, nil if all results were written
+	Error error
 }
 
 // PolicyValidationSummary contains violation and compliance result of a validate operation
@@ -67,6 +81,21 @@ type PolicyValidationSummary struct {
 	Violations  []PolicyValidation
 	Compliances []PolicyValidation
 	Mutation    *MutationResult
+	// SinkStatuses holds the delivery status of each configured sink, in the order the sinks were configured
+	SinkStatuses []SinkDeliveryStatus
+	// ConfigConflicts are the conflicts found while resolving the policy configs of the entity
+	ConfigConflicts []PolicyConfigConflict
+}
+
+// SinkErrors returns the errors returned by sinks while writing the results
+func (v *PolicyValidationSummary) SinkErrors() error {
+	var errs error
+	for _, status := range v.SinkStatuses {
+		if status.Error != nil {
+			errs = multierror.Append(errs, fmt.Errorf("sink %s: %w", status.Sink, status.Error))
+		}
+	}
+	return errs
 }
 
 // GetViolationMessages get all violation messages from review results
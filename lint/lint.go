@@ -0,0 +1,231 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"github.com/open-policy-agent/opa/ast"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+const (
+	RuleMissingID          = "missing-id"
+	RuleDuplicateID        = "duplicate-id"
+	RuleInvalidCode        = "invalid-code"
+	RuleMissingViolation   = "missing-violation-rule"
+	RuleUndeclaredParam    = "undeclared-parameter"
+	RuleUnusedParam        = "unused-parameter"
+	RuleInvalidSeverity    = "invalid-severity"
+	RuleInvalidMode        = "invalid-mode"
+	RuleIncompleteMutation = "incomplete-mutation"
+)
+
+const (
+	violationRule          = "violation"
+	violatingKeyField      = "violating_key"
+	recommendedValueField  = "recommended_value"
+	parametersInputField   = "parameters"
+	parametersInputPattern = "input.parameters"
+)
+
+// Finding is a problem found in a policy definition
+type Finding struct {
+	PolicyID string `json:"policy_id"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	// Location is the position of the problem in the policy code as row:col, if any
+	Location string `json:"location,omitempty"`
+}
+
+func (f Finding) String() string {
+	if f.Location != "" {
+		return fmt.Sprintf("%s: %s %s [%s]: %s", f.Severity, f.PolicyID, f.Location, f.Rule, f.Message)
+	}
+	return fmt.Sprintf("%s: %s [%s]: %s", f.Severity, f.PolicyID, f.Rule, f.Message)
+}
+
+// HasErrors checks if any of the findings is an error
+func HasErrors(findings []Finding) bool {
+	for _, finding := range findings {
+		if finding.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// LintAll lints the policies of a source, including checks across policies like duplicate ids
+func LintAll(policies []domain.Policy) []Finding {
+	var findings []Finding
+	seen := make(map[string]int)
+	for i := range policies {
+		findings = append(findings, Lint(policies[i])...)
+		if policies[i].ID == "" {
+			continue
+		}
+		seen[policies[i].ID]++
+		if seen[policies[i].ID] == 2 {
+			findings = append(findings, Finding{
+				PolicyID: policies[i].ID,
+				Rule:     RuleDuplicateID,
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("policy id %s is used by more than one policy", policies[i].ID),
+			})
+		}
+	}
+	return findings
+}
+
+// Lint checks a policy definition and returns the problems found in it
+func Lint(policy domain.Policy) []Finding {
+	l := linter{policy: policy}
+
+	if policy.ID == "" {
+		l.report(RuleMissingID, SeverityError, nil, "policy id is empty")
+	}
+	l.lintSeverity()
+	l.lintModes()
+
+	module, err := ast.ParseModule(policy.ID, policy.Code)
+	if err != nil {
+		l.report(RuleInvalidCode, SeverityError, nil, fmt.Sprintf("failed to parse policy code: %s", err))
+		return l.findings
+	}
+	if module == nil {
+		l.report(RuleInvalidCode, SeverityError, nil, "policy code is empty")
+		return l.findings
+	}
+
+	l.lintViolationRule(module)
+	l.lintParameters(module)
+	l.lintMutation(module)
+	return l.findings
+}
+
+type linter struct {
+	policy   domain.Policy
+	findings []Finding
+}
+
+func (l *linter) report(rule, severity string, location *ast.Location, message string) {
+	finding := Finding{
+		PolicyID: l.policy.ID,
+		Rule:     rule,
+		Severity: severity,
+		Message:  message,
+	}
+	if location != nil {
+		finding.Location = fmt.Sprintf("%d:%d", location.Row, location.Col)
+	}
+	l.findings = append(l.findings, finding)
+}
+
+func (l *linter) lintSeverity() {
+	if !contains(domain.PolicySeverities, l.policy.Severity) {
+		l.report(RuleInvalidSeverity, SeverityError, nil, fmt.Sprintf(
+			"severity %q is not one of %s", l.policy.Severity, strings.Join(domain.PolicySeverities, ", ")))
+	}
+}
+
+func (l *linter) lintModes() {
+	for _, mode := range l.policy.Modes {
+		if !contains(domain.PolicyModes, mode) {
+			l.report(RuleInvalidMode, SeverityError, nil, fmt.Sprintf(
+				"mode %q is not one of %s", mode, strings.Join(domain.PolicyModes, ", ")))
+		}
+	}
+}
+
+func (l *linter) lintViolationRule(module *ast.Module) {
+	for _, rule := range module.Rules {
+		if rule.Head.Name.String() == violationRule {
+			return
+		}
+	}
+	l.report(RuleMissingViolation, SeverityError, module.Package.Location, "policy has no violation rule")
+}
+
+// lintParameters matches the input.parameters references of the code with the declared parameters
+func (l *linter) lintParameters(module *ast.Module) {
+	declared := make(map[string]struct{}, len(l.policy.Parameters))
+	for _, param := range l.policy.Parameters {
+		declared[param.Name] = struct{}{}
+	}
+
+	referenced := make(map[string]struct{})
+	ast.WalkRefs(module, func(ref ast.Ref) bool {
+		if len(ref) < 3 || !ref[0].Equal(ast.InputRootDocument) || !ref[1].Equal(ast.StringTerm(parametersInputField)) {
+			return false
+		}
+		name, ok := ref[2].Value.(ast.String)
+		if !ok {
+			return false
+		}
+		if _, ok := referenced[string(name)]; ok {
+			return false
+		}
+		referenced[string(name)] = struct{}{}
+		if _, ok := declared[string(name)]; !ok {
+			l.report(RuleUndeclaredParam, SeverityError, ref[0].Location, fmt.Sprintf(
+				"parameter %s is referenced by the code but not declared", name))
+		}
+		return false
+	})
+
+	// parameters could be accessed dynamically, e.g. input.parameters[name]
+	if strings.Contains(l.policy.Code, parametersInputPattern+"[") {
+		return
+	}
+	for _, param := range l.policy.Parameters {
+		if _, ok := referenced[param.Name]; !ok {
+			l.report(RuleUnusedParam, SeverityWarning, nil, fmt.Sprintf(
+				"parameter %s is declared but not referenced by the code", param.Name))
+		}
+	}
+}
+
+// lintMutation checks that the violations of mutating policies hold the fields needed to fix the entity
+func (l *linter) lintMutation(module *ast.Module) {
+	if !l.policy.Mutate {
+		return
+	}
+
+	// violations could be built by helper rules, so look for the fields in the whole module
+	fields := make(map[string]struct{})
+	ast.WalkTerms(module, func(term *ast.Term) bool {
+		if obj, ok := term.Value.(ast.Object); ok {
+			obj.Foreach(func(key, _ *ast.Term) {
+				if field, ok := key.Value.(ast.String); ok {
+					fields[string(field)] = struct{}{}
+				}
+			})
+		}
+		return false
+	})
+
+	var missing []string
+	for _, field := range []string{violatingKeyField, recommendedValueField} {
+		if _, ok := fields[field]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		l.report(RuleIncompleteMutation, SeverityError, module.Package.Location, fmt.Sprintf(
+			"mutating policy violations do not set %s", strings.Join(missing, ", ")))
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
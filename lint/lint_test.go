@@ -0,0 +1,137 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+const validCode = `
+package weave.test.replicas
+
+min_replicas := input.parameters.replicas
+
+violation[result] {
+	input.review.object.spec.replicas < min_replicas
+	result := {
+		"msg": "not enough replicas",
+		"violating_key": "spec.replicas",
+		"recommended_value": min_replicas
+	}
+}
+`
+
+func validPolicy() domain.Policy {
+	return domain.Policy{
+		ID:         "weave.test.replicas",
+		Code:       validCode,
+		Severity:   domain.PolicySeverityHigh,
+		Modes:      []string{domain.PolicyModeAdmission, domain.PolicyModeAudit},
+		Mutate:     true,
+		Parameters: []domain.PolicyParameters{{Name: "replicas", Type: "integer"}},
+	}
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(policy *domain.Policy)
+		rules  []string
+	}{
+		{
+			name:   "valid policy",
+			modify: func(policy *domain.Policy) {},
+		},
+		{
+			name:   "missing id",
+			modify: func(policy *domain.Policy) { policy.ID = "" },
+			rules:  []string{RuleMissingID},
+		},
+		{
+			name:   "invalid code",
+			modify: func(policy *domain.Policy) { policy.Code = "package test\nviolation[result] {" },
+			rules:  []string{RuleInvalidCode},
+		},
+		{
+			name:   "empty code",
+			modify: func(policy *domain.Policy) { policy.Code = "" },
+			rules:  []string{RuleInvalidCode},
+		},
+		{
+			name: "missing violation rule",
+			modify: func(policy *domain.Policy) {
+				policy.Mutate = false
+				policy.Code = "package test\n\nreplicas := input.parameters.replicas\ndeny[msg] { msg := \"denied\" }"
+			},
+			rules: []string{RuleMissingViolation},
+		},
+		{
+			name: "undeclared and unused parameters",
+			modify: func(policy *domain.Policy) {
+				policy.Parameters = []domain.PolicyParameters{{Name: "replica_count"}}
+			},
+			rules: []string{RuleUndeclaredParam, RuleUnusedParam},
+		},
+		{
+			name: "dynamic parameters access",
+			modify: func(policy *domain.Policy) {
+				policy.Mutate = false
+				policy.Code = "package test\n\nviolation[result] { some name\n result := input.parameters[name] }"
+			},
+		},
+		{
+			name:   "invalid severity",
+			modify: func(policy *domain.Policy) { policy.Severity = "urgent" },
+			rules:  []string{RuleInvalidSeverity},
+		},
+		{
+			name:   "invalid mode",
+			modify: func(policy *domain.Policy) { policy.Modes = []string{domain.PolicyModeAudit, "runtime"} },
+			rules:  []string{RuleInvalidMode},
+		},
+		{
+			name: "incomplete mutation",
+			modify: func(policy *domain.Policy) {
+				policy.Code = "package test\n\nviolation[result] { result := {\"msg\": \"denied\", \"replicas\": input.parameters.replicas} }"
+			},
+			rules: []string{RuleIncompleteMutation},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := validPolicy()
+			tt.modify(&policy)
+			var rules []string
+			for _, finding := range Lint(policy) {
+				rules = append(rules, finding.Rule)
+			}
+			assert.Equal(t, tt.rules, rules)
+		})
+	}
+}
+
+func TestLint_Location(t *testing.T) {
+	policy := validPolicy()
+	policy.Parameters = nil
+	findings := Lint(policy)
+	assert.Len(t, findings, 1)
+	assert.Equal(t, RuleUndeclaredParam, findings[0].Rule)
+	assert.Equal(t, "4:17", findings[0].Location)
+	assert.True(t, HasErrors(findings))
+}
+
+func TestLintAll(t *testing.T) {
+	unused := validPolicy()
+	unused.ID = "weave.test.unused"
+	unused.Parameters = append(unused.Parameters, domain.PolicyParameters{Name: "unused"})
+
+	findings := LintAll([]domain.Policy{validPolicy(), unused, validPolicy(), validPolicy()})
+	assert.Len(t, findings, 2)
+	assert.Equal(t, RuleUnusedParam, findings[0].Rule)
+	assert.Equal(t, SeverityWarning, findings[0].Severity)
+	assert.Equal(t, RuleDuplicateID, findings[1].Rule)
+	assert.Equal(t, "weave.test.replicas", findings[1].PolicyID)
+	assert.False(t, HasErrors(findings[:1]))
+}
@@ -0,0 +1,313 @@
+package policytest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"github.com/MagalixTechnologies/policy-core/validation"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	ExpectViolation = "violation"
+	ExpectCompliant = "compliant"
+
+	validationType = "PolicyTest"
+	trigger        = "PolicyTest"
+)
+
+// Case is a fixture manifest and the expected result of validating it against the suite policy
+type Case struct {
+	Name string `json:"name"`
+	// Fixture is the path of the json or yaml manifest, relative to the suite file
+	Fixture string `json:"fixture"`
+	// Expect is either violation or compliant
+	Expect string `json:"expect"`
+	// Messages are the expected occurrences messages of a violation, checked when set
+	Messages []string `json:"messages,omitempty"`
+	// Mutated is the path of the expected manifest after mutation, relative to the suite file, checked when set
+	Mutated string `json:"mutated,omitempty"`
+	// Parameters override the policy parameters values for this case
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// Suite holds the test cases of a policy
+type Suite struct {
+	// Policy is the id of the tested policy
+	Policy string `json:"policy"`
+	Cases  []Case `json:"cases"`
+	// Dir is the directory fixtures paths are relative to, defaults to the suite file directory
+	Dir string `json:"-"`
+}
+
+// LoadSuite reads a test suite from a json or yaml file
+func LoadSuite(path string) (*Suite, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test suite %s: %w", path, err)
+	}
+	var suite Suite
+	if err := yaml.Unmarshal(raw, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse test suite %s: %w", path, err)
+	}
+	if suite.Policy == "" {
+		return nil, fmt.Errorf("test suite %s has no policy", path)
+	}
+	for i, c := range suite.Cases {
+		if c.Expect != ExpectViolation && c.Expect != ExpectCompliant {
+			return nil, fmt.Errorf("case %d of test suite %s expects %q, must be %s or %s", i, path, c.Expect, ExpectViolation, ExpectCompliant)
+		}
+	}
+	suite.Dir = filepath.Dir(path)
+	return &suite, nil
+}
+
+// LoadPolicies reads policies definitions from a json or yaml file holding a list of policies
+func LoadPolicies(path string) ([]domain.Policy, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policies %s: %w", path, err)
+	}
+	var policies []domain.Policy
+	if err := yaml.Unmarshal(raw, &policies); err != nil {
+		return nil, fmt.Errorf("failed to parse policies %s: %w", path, err)
+	}
+	return policies, nil
+}
+
+// CaseResult is the outcome of running a test case
+type CaseResult struct {
+	Policy string `json:"policy"`
+	Case   string `json:"case"`
+	// Failures describe the mismatches between the expected and actual results, empty when the case passed
+	Failures []string `json:"failures,omitempty"`
+}
+
+// Passed checks if the case matched its expectations
+func (r CaseResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Report holds the results of running test suites
+type Report struct {
+	Results []CaseResult `json:"results"`
+}
+
+// Failed checks if any case of the report failed
+func (r *Report) Failed() bool {
+	for _, result := range r.Results {
+		if !result.Passed() {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes a human readable report
+func (r *Report) Print(w io.Writer) {
+	var failed int
+	for _, result := range r.Results {
+		if result.Passed() {
+			fmt.Fprintf(w, "PASS %s/%s\n", result.Policy, result.Case)
+			continue
+		}
+		failed++
+		fmt.Fprintf(w, "FAIL %s/%s\n", result.Policy, result.Case)
+		for _, failure := range result.Failures {
+			fmt.Fprintf(w, "    %s\n", failure)
+		}
+	}
+	fmt.Fprintf(w, "%d passed, %d failed\n", len(r.Results)-failed, failed)
+}
+
+// TestingT is the subset of testing.T used to report failed cases
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Assert reports every failed case of the report on t, to run suites from go test
+func (r *Report) Assert(t TestingT) {
+	t.Helper()
+	for _, result := range r.Results {
+		if !result.Passed() {
+			t.Errorf("%s/%s: %s", result.Policy, result.Case, strings.Join(result.Failures, "; "))
+		}
+	}
+}
+
+// Run runs the test suites against their policies
+func Run(ctx context.Context, policies []domain.Policy, suites ...*Suite) *Report {
+	byID := make(map[string]domain.Policy, len(policies))
+	for _, policy := range policies {
+		byID[policy.ID] = policy
+	}
+
+	report := &Report{}
+	for _, suite := range suites {
+		policy, ok := byID[suite.Policy]
+		if !ok {
+			report.Results = append(report.Results, CaseResult{
+				Policy:   suite.Policy,
+				Failures: []string{fmt.Sprintf("policy %s is not found", suite.Policy)},
+			})
+			continue
+		}
+		for _, c := range suite.Cases {
+			report.Results = append(report.Results, runCase(ctx, policy, suite.Dir, c))
+		}
+	}
+	return report
+}
+
+func runCase(ctx context.Context, policy domain.Policy, dir string, c Case) CaseResult {
+	result := CaseResult{Policy: policy.ID, Case: c.Name}
+	if result.Case == "" {
+		result.Case = c.Fixture
+	}
+	fail := func(format string, args ...interface{}) CaseResult {
+		result.Failures = append(result.Failures, fmt.Sprintf(format, args...))
+		return result
+	}
+
+	entity, err := loadEntity(filepath.Join(dir, c.Fixture))
+	if err != nil {
+		return fail("%s", err)
+	}
+
+	policy = withParameters(policy, c.Parameters)
+	source := &staticSource{policies: []domain.Policy{policy}}
+
+	summary, err := validation.NewOPAValidator(source, true, validationType, "", "", false).
+		Validate(ctx, entity, trigger)
+	if err != nil {
+		return fail("validation failed: %s", err)
+	}
+
+	if len(summary.Violations) == 0 && len(summary.Compliances) == 0 {
+		return fail("fixture %s is not targeted by the policy", c.Fixture)
+	}
+	if c.Expect == ExpectViolation && len(summary.Violations) == 0 {
+		fail("expected a violation, found compliant")
+	}
+	if c.Expect == ExpectCompliant && len(summary.Violations) > 0 {
+		fail("expected compliant, found violation: %s", summary.Violations[0].Message)
+	}
+
+	if c.Messages != nil {
+		var messages []string
+		for _, violation := range summary.Violations {
+			for _, occurrence := range violation.Occurrences {
+				messages = append(messages, occurrence.Message)
+			}
+		}
+		expected := append([]string(nil), c.Messages...)
+		sort.Strings(expected)
+		sort.Strings(messages)
+		if !reflect.DeepEqual(expected, messages) {
+			fail("expected occurrences messages %q, found %q", expected, messages)
+		}
+	}
+
+	if c.Mutated != "" {
+		if err := checkMutation(ctx, source, entity, filepath.Join(dir, c.Mutated)); err != nil {
+			fail("%s", err)
+		}
+	}
+	return result
+}
+
+// checkMutation validates the entity with mutation enabled and compares the mutated manifest with the expected one
+func checkMutation(ctx context.Context, source domain.PoliciesSource, entity domain.Entity, path string) error {
+	expected, err := loadManifest(path)
+	if err != nil {
+		return err
+	}
+
+	summary, err := validation.NewOPAValidator(source, false, validationType, "", "", true).
+		Validate(ctx, entity, trigger)
+	if err != nil {
+		return fmt.Errorf("mutation failed: %w", err)
+	}
+	if summary.Mutation == nil {
+		return fmt.Errorf("expected a mutation, found none")
+	}
+	raw, err := summary.Mutation.NewResource()
+	if err != nil {
+		return fmt.Errorf("failed to get mutated resource: %w", err)
+	}
+	var mutated map[string]interface{}
+	if err := json.Unmarshal(raw, &mutated); err != nil {
+		return fmt.Errorf("failed to parse mutated resource: %w", err)
+	}
+	if !reflect.DeepEqual(expected, mutated) {
+		return fmt.Errorf("expected mutated resource %s, found %s", mustJSON(expected), raw)
+	}
+	return nil
+}
+
+// withParameters returns a copy of the policy with the given parameters values
+func withParameters(policy domain.Policy, parameters map[string]interface{}) domain.Policy {
+	policy.Parameters = append([]domain.PolicyParameters(nil), policy.Parameters...)
+	for i := range policy.Parameters {
+		if value, ok := parameters[policy.Parameters[i].Name]; ok {
+			policy.Parameters[i].Value = value
+		}
+	}
+	return policy
+}
+
+func loadEntity(path string) (domain.Entity, error) {
+	manifest, err := loadManifest(path)
+	if err != nil {
+		return domain.Entity{}, err
+	}
+	entity, err := domain.NewEntityFromManifest(manifest)
+	if err != nil {
+		return domain.Entity{}, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	return entity, nil
+}
+
+// loadManifest reads a json or yaml manifest, values are normalized to their json representation
+func loadManifest(path string) (map[string]interface{}, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture %s: %w", path, err)
+	}
+	raw, err = yaml.YAMLToJSON(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+	}
+	var manifest map[string]interface{}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+func mustJSON(v interface{}) string {
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
+
+// staticSource serves a fixed list of policies without any config
+type staticSource struct {
+	policies []domain.Policy
+}
+
+func (s *staticSource) GetAll(ctx context.Context) ([]domain.Policy, error) {
+	return s.policies, nil
+}
+
+func (s *staticSource) GetPolicyConfig(ctx context.Context, entity domain.Entity) (*domain.PolicyConfig, error) {
+	return nil, nil
+}
@@ -0,0 +1,51 @@
+package policytest
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	assert := require.New(t)
+	policies, err := LoadPolicies("testdata/policies.yaml")
+	assert.Nil(err)
+	assert.Len(policies, 1)
+
+	suite, err := LoadSuite("testdata/replicas_test.yaml")
+	assert.Nil(err)
+	report := Run(context.Background(), policies, suite)
+	assert.Len(report.Results, 3)
+	assert.False(report.Failed(), "%+v", report.Results)
+	report.Assert(t)
+
+	suite, err = LoadSuite("testdata/failing_test.yaml")
+	assert.Nil(err)
+	report = Run(context.Background(), policies, suite, &Suite{Policy: "missing"})
+	assert.True(report.Failed())
+	assert.Len(report.Results, 5)
+	assert.Contains(report.Results[0].Failures[0], "expected compliant, found violation")
+	assert.Contains(report.Results[1].Failures[0], "expected occurrences messages")
+	assert.Contains(report.Results[2].Failures[0], "expected mutated resource")
+	assert.Contains(report.Results[3].Failures[0], "failed to read fixture")
+	assert.Contains(report.Results[4].Failures[0], "policy missing is not found")
+
+	var out bytes.Buffer
+	report.Print(&out)
+	assert.Contains(out.String(), "FAIL weave.policies.replicas/wrong expectation")
+	assert.Contains(out.String(), "0 passed, 5 failed")
+}
+
+func TestLoadSuite(t *testing.T) {
+	assert := require.New(t)
+	_, err := LoadSuite("testdata/missing_test.yaml")
+	assert.Error(err)
+
+	suite, err := LoadSuite("testdata/replicas_test.yaml")
+	assert.Nil(err)
+	assert.Equal("testdata", suite.Dir)
+	assert.Equal(ExpectViolation, suite.Cases[1].Expect)
+	assert.Equal(float64(5), suite.Cases[2].Parameters["replica_count"])
+}
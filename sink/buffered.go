@@ -0,0 +1,396 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/MagalixTechnologies/core/logger"
+	"github.com/MagalixTechnologies/policy-core/domain"
+)
+
+const (
+	defaultBufferSize      = 1000
+	defaultBatchSize       = 100
+	defaultFlushInterval   = 5 * time.Second
+	defaultRetryBackoff    = 100 * time.Millisecond
+	defaultMaxRetryBackoff = 10 * time.Second
+)
+
+// OverflowPolicy defines what happens to results written while the buffer is full
+type OverflowPolicy string
+
+const (
+	// OverflowDrop drops results that do not fit in the buffer
+	OverflowDrop OverflowPolicy = "drop"
+	// OverflowSpill appends results that do not fit in the buffer to a file on disk
+	// and writes them to the wrapped sink once the buffer has been flushed
+	OverflowSpill OverflowPolicy = "spill"
+)
+
+var (
+	// ErrBufferFull is returned when results are dropped because the buffer is full
+	ErrBufferFull = errors.New("sink buffer is full")
+	// ErrSinkClosed is returned when writing to a closed sink
+	ErrSinkClosed = errors.New("sink is closed")
+)
+
+// BufferedSinkConfig configures a BufferedSink
+type BufferedSinkConfig struct {
+	// BufferSize is the maximum number of results waiting in memory to be written
+	BufferSize int
+	// BatchSize is the maximum number of results written to the wrapped sink at once
+	BatchSize int
+	// FlushInterval is the maximum time a result waits in the buffer before being written
+	FlushInterval time.Duration
+	// MaxRetries is the number of times a failed batch is retried before it is reported
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry, it is doubled on every retry
+	RetryBackoff time.Duration
+	// MaxRetryBackoff caps the wait between retries
+	MaxRetryBackoff time.Duration
+	// Overflow defines what happens to results written while the buffer is full
+	Overflow OverflowPolicy
+	// SpillPath is the file overflowing results are appended to when Overflow is OverflowSpill,
+	// results being replayed are kept in SpillPath with a .replay suffix until they are written
+	SpillPath string
+	// OnError is called with the results that could not be delivered to the wrapped sink
+	OnError func(err error, results []domain.PolicyValidation)
+}
+
+// BufferedSink queues results and writes them to the wrapped sink in batches
+// from a background goroutine, retrying failed batches with backoff
+type BufferedSink struct {
+	sink   domain.PolicyValidationSink
+	config BufferedSinkConfig
+
+	mu     sync.RWMutex
+	closed bool
+
+	spillMu sync.Mutex
+
+	queue   chan domain.PolicyValidation
+	flushCh chan chan struct{}
+	closeCh chan struct{}
+	stopped chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewBufferedSink returns a buffered sink wrapping the given sink and starts its background writer
+func NewBufferedSink(sink domain.PolicyValidationSink, config BufferedSinkConfig) (*BufferedSink, error) {
+	if config.BufferSize <= 0 {
+		config.BufferSize = defaultBufferSize
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaultFlushInterval
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = defaultRetryBackoff
+	}
+	if config.MaxRetryBackoff <= 0 {
+		config.MaxRetryBackoff = defaultMaxRetryBackoff
+	}
+	switch config.Overflow {
+	case "":
+		config.Overflow = OverflowDrop
+	case OverflowDrop:
+	case OverflowSpill:
+		if config.SpillPath == "" {
+			return nil, fmt.Errorf("spill path is required when overflow policy is %s", OverflowSpill)
+		}
+	default:
+		return nil, fmt.Errorf("unknown overflow policy %s", config.Overflow)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &BufferedSink{
+		sink:    sink,
+		config:  config,
+		queue:   make(chan domain.PolicyValidation, config.BufferSize),
+		flushCh: make(chan chan struct{}),
+		closeCh: make(chan struct{}),
+		stopped: make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write queues results to be written to the wrapped sink, implements domain.PolicyValidationSink
+func (s *BufferedSink) Write(ctx context.Context, results []domain.PolicyValidation) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return ErrSinkClosed
+	}
+
+	var overflow []domain.PolicyValidation
+	for _, result := range results {
+		select {
+		case s.queue <- result:
+		default:
+			overflow = append(overflow, result)
+		}
+	}
+	if len(overflow) == 0 {
+		return nil
+	}
+
+	if s.config.Overflow == OverflowSpill {
+		if err := s.spill(overflow); err != nil {
+			err = fmt.Errorf("failed to spill %d results to %s: %w", len(overflow), s.config.SpillPath, err)
+			s.reportError(err, overflow)
+			return err
+		}
+		return nil
+	}
+
+	err := fmt.Errorf("%w: dropped %d results", ErrBufferFull, len(overflow))
+	s.reportError(err, overflow)
+	return err
+}
+
+// Flush writes all queued and spilled results to the wrapped sink and waits until it is done
+func (s *BufferedSink) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case s.flushCh <- done:
+	case <-s.stopped:
+		return ErrSinkClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting results, flushes the queued ones and stops the background writer.
+// Pending retries are aborted if ctx is done before flushing finishes.
+func (s *BufferedSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.closeCh)
+	s.mu.Unlock()
+
+	select {
+	case <-s.stopped:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		<-s.stopped
+		return ctx.Err()
+	}
+}
+
+func (s *BufferedSink) run() {
+	defer close(s.stopped)
+	// results left over by a replay interrupted before they were all written are replayed first
+	if s.config.Overflow == OverflowSpill {
+		s.replayFile(s.replayPath())
+	}
+
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]domain.PolicyValidation, 0, s.config.BatchSize)
+	for {
+		select {
+		case result := <-s.queue:
+			batch = append(batch, result)
+			if len(batch) >= s.config.BatchSize {
+				batch = s.flush(batch, false)
+			}
+		case <-ticker.C:
+			batch = s.flush(batch, false)
+		case done := <-s.flushCh:
+			batch = s.flush(s.drain(batch), true)
+			close(done)
+		case <-s.closeCh:
+			s.flush(s.drain(batch), true)
+			return
+		}
+	}
+}
+
+// drain moves all queued results to the batch
+func (s *BufferedSink) drain(batch []domain.PolicyValidation) []domain.PolicyValidation {
+	for {
+		select {
+		case result := <-s.queue:
+			batch = append(batch, result)
+		default:
+			return batch
+		}
+	}
+}
+
+// flush writes the batch and, when the queue is drained or the flush is explicit, any spilled results.
+// It returns an empty batch to be reused
+func (s *BufferedSink) flush(batch []domain.PolicyValidation, explicit bool) []domain.PolicyValidation {
+	for start := 0; start < len(batch); start += s.config.BatchSize {
+		end := start + s.config.BatchSize
+		if end > len(batch) {
+			end = len(batch)
+		}
+		chunk := make([]domain.PolicyValidation, end-start)
+		copy(chunk, batch[start:end])
+		s.deliver(chunk)
+	}
+	// spilled results are only replayed once the queue they overflowed from is drained
+	if s.config.Overflow == OverflowSpill && (explicit || len(s.queue) == 0) {
+		s.replaySpill()
+	}
+	return batch[:0]
+}
+
+// deliver writes results to the wrapped sink, retrying with backoff on failure
+func (s *BufferedSink) deliver(results []domain.PolicyValidation) bool {
+	backoff := s.config.RetryBackoff
+	var err error
+	for attempt := 0; attempt <= s.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-s.ctx.Done():
+				s.reportError(fmt.Errorf("aborted retrying sink write: %w", err), results)
+				return false
+			}
+			backoff *= 2
+			if backoff > s.config.MaxRetryBackoff {
+				backoff = s.config.MaxRetryBackoff
+			}
+		}
+		err = s.sink.Write(s.ctx, results)
+		if err == nil {
+			return true
+		}
+		logger.Warnw("failed to write results to sink", "attempt", attempt+1, "error", err)
+	}
+	s.reportError(
+		fmt.Errorf("failed to write %d results after %d attempts: %w", len(results), s.config.MaxRetries+1, err),
+		results,
+	)
+	return false
+}
+
+// spill appends results to the spill file as JSON lines
+func (s *BufferedSink) spill(results []domain.PolicyValidation) error {
+	s.spillMu.Lock()
+	defer s.spillMu.Unlock()
+
+	file, err := os.OpenFile(s.config.SpillPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, result := range results {
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaySpill writes spilled results to the wrapped sink. The spill file is moved to the replay file
+// so that overflowing writes are not blocked while retrying, the replay file is removed once all of
+// its results are written or reported as failed and is replayed again on startup if it is left over
+func (s *BufferedSink) replaySpill() {
+	replayPath := s.replayPath()
+	if _, err := os.Stat(replayPath); err == nil {
+		s.replayFile(replayPath)
+		// the replay file is kept when replaying is aborted, it must not be overwritten
+		if _, err := os.Stat(replayPath); err == nil {
+			return
+		}
+	}
+
+	s.spillMu.Lock()
+	err := os.Rename(s.config.SpillPath, replayPath)
+	s.spillMu.Unlock()
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Errorw("failed to move spill file", "path", s.config.SpillPath, "error", err)
+		}
+		return
+	}
+	s.replayFile(replayPath)
+}
+
+// replayFile writes the results spilled to the file to the wrapped sink and removes the file,
+// the file is kept to be replayed on startup when the sink is closed before all results are written
+func (s *BufferedSink) replayFile(path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Errorw("failed to open spill file", "path", path, "error", err)
+		}
+		return
+	}
+
+	var results []domain.PolicyValidation
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var result domain.PolicyValidation
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			logger.Errorw("failed to decode spilled result", "path", path, "error", err)
+			continue
+		}
+		results = append(results, result)
+	}
+	file.Close()
+	if err := scanner.Err(); err != nil {
+		logger.Errorw("failed to read spill file", "path", path, "error", err)
+		return
+	}
+
+	for start := 0; start < len(results); start += s.config.BatchSize {
+		end := start + s.config.BatchSize
+		if end > len(results) {
+			end = len(results)
+		}
+		if !s.deliver(results[start:end]) && s.ctx.Err() != nil {
+			logger.Warnw("keeping spill file to replay on startup", "path", path)
+			return
+		}
+	}
+
+	if err := os.Remove(path); err != nil {
+		logger.Errorw("failed to remove spill file", "path", path, "error", err)
+	}
+}
+
+// replayPath is the file spilled results are moved to while being replayed
+func (s *BufferedSink) replayPath() string {
+	return s.config.SpillPath + ".replay"
+}
+
+func (s *BufferedSink) reportError(err error, results []domain.PolicyValidation) {
+	if s.config.OnError != nil {
+		s.config.OnError(err, results)
+		return
+	}
+	logger.Errorw("failed to deliver results", "count", len(results), "error", err)
+}
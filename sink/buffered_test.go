@@ -0,0 +1,301 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSink struct {
+	mu       sync.Mutex
+	batches  [][]domain.PolicyValidation
+	failures int
+	attempts int
+	block    chan struct{}
+}
+
+func (f *fakeSink) Write(ctx context.Context, results []domain.PolicyValidation) error {
+	if f.block != nil {
+		<-f.block
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts++
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("backend unavailable")
+	}
+	f.batches = append(f.batches, results)
+	return nil
+}
+
+func (f *fakeSink) written() []domain.PolicyValidation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var results []domain.PolicyValidation
+	for _, batch := range f.batches {
+		results = append(results, batch...)
+	}
+	return results
+}
+
+func newResults(count int) []domain.PolicyValidation {
+	results := make([]domain.PolicyValidation, count)
+	for i := range results {
+		results[i] = domain.PolicyValidation{ID: string(rune('a' + i))}
+	}
+	return results
+}
+
+func TestBufferedSink_Batching(t *testing.T) {
+	assert := require.New(t)
+	backend := &fakeSink{}
+	s, err := NewBufferedSink(backend, BufferedSinkConfig{
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	})
+	assert.Nil(err)
+
+	assert.Nil(s.Write(context.Background(), newResults(5)))
+	assert.Nil(s.Flush(context.Background()))
+
+	backend.mu.Lock()
+	assert.Len(backend.batches, 3)
+	assert.Len(backend.batches[0], 2)
+	assert.Len(backend.batches[2], 1)
+	backend.mu.Unlock()
+
+	assert.Nil(s.Close(context.Background()))
+	assert.ErrorIs(s.Write(context.Background(), newResults(1)), ErrSinkClosed)
+}
+
+func TestBufferedSink_FlushInterval(t *testing.T) {
+	assert := require.New(t)
+	backend := &fakeSink{}
+	s, err := NewBufferedSink(backend, BufferedSinkConfig{
+		BatchSize:     10,
+		FlushInterval: 10 * time.Millisecond,
+	})
+	assert.Nil(err)
+	defer s.Close(context.Background())
+
+	assert.Nil(s.Write(context.Background(), newResults(3)))
+	assert.Eventually(func() bool {
+		return len(backend.written()) == 3
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestBufferedSink_Retry(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int
+		maxRetries int
+		written    int
+		reported   int
+	}{
+		{
+			name:       "succeeds after retrying",
+			failures:   2,
+			maxRetries: 2,
+			written:    2,
+		},
+		{
+			name:       "reports error after retries",
+			failures:   3,
+			maxRetries: 2,
+			reported:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := require.New(t)
+			backend := &fakeSink{failures: tt.failures}
+			var reported int
+			s, err := NewBufferedSink(backend, BufferedSinkConfig{
+				FlushInterval: time.Hour,
+				MaxRetries:    tt.maxRetries,
+				RetryBackoff:  time.Millisecond,
+				OnError: func(err error, results []domain.PolicyValidation) {
+					reported += len(results)
+				},
+			})
+			assert.Nil(err)
+
+			assert.Nil(s.Write(context.Background(), newResults(2)))
+			assert.Nil(s.Close(context.Background()))
+			assert.Len(backend.written(), tt.written)
+			assert.Equal(tt.reported, reported)
+		})
+	}
+}
+
+func TestBufferedSink_Overflow(t *testing.T) {
+	assert := require.New(t)
+	backend := &fakeSink{block: make(chan struct{})}
+	var mu sync.Mutex
+	var dropped int
+	s, err := NewBufferedSink(backend, BufferedSinkConfig{
+		BufferSize:    2,
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+		OnError: func(err error, results []domain.PolicyValidation) {
+			mu.Lock()
+			defer mu.Unlock()
+			dropped += len(results)
+		},
+	})
+	assert.Nil(err)
+
+	// the background writer holds one result while blocked on the backend
+	assert.Nil(s.Write(context.Background(), newResults(1)))
+	assert.Eventually(func() bool { return len(s.queue) == 0 }, time.Second, time.Millisecond)
+
+	err = s.Write(context.Background(), newResults(4))
+	assert.ErrorIs(err, ErrBufferFull)
+	mu.Lock()
+	assert.Equal(2, dropped)
+	mu.Unlock()
+
+	close(backend.block)
+	assert.Nil(s.Close(context.Background()))
+	assert.Len(backend.written(), 3)
+}
+
+func TestBufferedSink_Spill(t *testing.T) {
+	assert := require.New(t)
+	backend := &fakeSink{block: make(chan struct{})}
+	s, err := NewBufferedSink(backend, BufferedSinkConfig{
+		BufferSize:    1,
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+		Overflow:      OverflowSpill,
+		SpillPath:     filepath.Join(t.TempDir(), "spill.jsonl"),
+	})
+	assert.Nil(err)
+
+	assert.Nil(s.Write(context.Background(), newResults(1)))
+	assert.Eventually(func() bool { return len(s.queue) == 0 }, time.Second, time.Millisecond)
+	assert.Nil(s.Write(context.Background(), newResults(4)))
+
+	close(backend.block)
+	assert.Nil(s.Close(context.Background()))
+
+	written := backend.written()
+	assert.Len(written, 5)
+	ids := map[string]int{}
+	for _, result := range written {
+		ids[result.ID]++
+	}
+	assert.Equal(map[string]int{"a": 2, "b": 1, "c": 1, "d": 1}, ids)
+}
+
+func TestBufferedSink_SpillReplayedAfterQueue(t *testing.T) {
+	assert := require.New(t)
+	backend := &fakeSink{block: make(chan struct{})}
+	s, err := NewBufferedSink(backend, BufferedSinkConfig{
+		BufferSize:    2,
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+		Overflow:      OverflowSpill,
+		SpillPath:     filepath.Join(t.TempDir(), "spill.jsonl"),
+	})
+	assert.Nil(err)
+
+	assert.Nil(s.Write(context.Background(), newResults(1)))
+	assert.Eventually(func() bool { return len(s.queue) == 0 }, time.Second, time.Millisecond)
+	// a and b are queued while c overflows to the spill file
+	assert.Nil(s.Write(context.Background(), newResults(3)))
+
+	close(backend.block)
+	assert.Eventually(func() bool { return len(backend.written()) == 4 }, time.Second, time.Millisecond)
+	assert.Nil(s.Close(context.Background()))
+
+	var ids []string
+	for _, result := range backend.written() {
+		ids = append(ids, result.ID)
+	}
+	assert.Equal([]string{"a", "a", "b", "c"}, ids)
+}
+
+func TestBufferedSink_SpillWhileReplaying(t *testing.T) {
+	assert := require.New(t)
+	backend := &fakeSink{failures: 1 << 30}
+	spillPath := filepath.Join(t.TempDir(), "spill.jsonl")
+	s, err := NewBufferedSink(backend, BufferedSinkConfig{
+		BufferSize:    1,
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+		MaxRetries:    10,
+		RetryBackoff:  time.Hour,
+		Overflow:      OverflowSpill,
+		SpillPath:     spillPath,
+		OnError:       func(err error, results []domain.PolicyValidation) {},
+	})
+	assert.Nil(err)
+	assert.Nil(s.spill(newResults(2)))
+
+	// the background writer keeps retrying the spilled results while the backend fails
+	go s.Flush(context.Background())
+	assert.Eventually(func() bool {
+		backend.mu.Lock()
+		defer backend.mu.Unlock()
+		return backend.attempts > 0
+	}, time.Second, time.Millisecond)
+
+	written := make(chan error, 1)
+	go func() {
+		written <- s.Write(context.Background(), newResults(2))
+	}()
+	select {
+	case err := <-written:
+		assert.Nil(err)
+	case <-time.After(time.Second):
+		t.Fatal("write blocked while spilled results were being retried")
+	}
+	assert.Len(readSpillFile(t, s.replayPath()), 2)
+	assert.Len(readSpillFile(t, spillPath), 1)
+
+	// results not written before closing are kept on disk
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(s.Close(ctx), context.DeadlineExceeded)
+	assert.Len(readSpillFile(t, s.replayPath()), 2)
+	assert.Len(readSpillFile(t, spillPath), 1)
+
+	// and replayed by the next sink using the same spill path
+	backend = &fakeSink{}
+	s, err = NewBufferedSink(backend, BufferedSinkConfig{
+		FlushInterval: time.Hour,
+		Overflow:      OverflowSpill,
+		SpillPath:     spillPath,
+	})
+	assert.Nil(err)
+	assert.Eventually(func() bool { return len(backend.written()) == 2 }, time.Second, time.Millisecond)
+	assert.Nil(s.Close(context.Background()))
+	assert.Len(backend.written(), 3)
+	assert.NoFileExists(s.replayPath())
+	assert.NoFileExists(spillPath)
+}
+
+func readSpillFile(t *testing.T, path string) []string {
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestNewBufferedSink_InvalidConfig(t *testing.T) {
+	assert := require.New(t)
+	_, err := NewBufferedSink(&fakeSink{}, BufferedSinkConfig{Overflow: OverflowSpill})
+	assert.Error(err)
+	_, err = NewBufferedSink(&fakeSink{}, BufferedSinkConfig{Overflow: "unknown"})
+	assert.Error(err)
+}
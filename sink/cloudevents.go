@@ -0,0 +1,124 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+const (
+	cloudEventsContentType      = "application/cloudevents+json"
+	cloudEventsBatchContentType = "application/cloudevents-batch+json"
+)
+
+// CloudEventsMode is the HTTP content mode used to send cloud events
+type CloudEventsMode string
+
+const (
+	// CloudEventsStructured sends the whole event as the request body
+	CloudEventsStructured CloudEventsMode = "structured"
+	// CloudEventsBinary sends the event attributes as ce- headers and the event data as the request body
+	CloudEventsBinary CloudEventsMode = "binary"
+)
+
+// CloudEventsSinkConfig configures a CloudEventsSink
+type CloudEventsSinkConfig struct {
+	WebhookSinkConfig
+	// Mode is the HTTP content mode, defaults to structured
+	Mode CloudEventsMode
+	// Batch sends all the results of a write in a single request, only supported in structured mode
+	Batch bool
+}
+
+// CloudEventsSink posts results as CloudEvents 1.0 to an HTTP endpoint
+type CloudEventsSink struct {
+	webhook *WebhookSink
+	mode    CloudEventsMode
+	batch   bool
+}
+
+// NewCloudEventsSink returns a sink posting results as cloud events to the configured URL
+func NewCloudEventsSink(config CloudEventsSinkConfig) (*CloudEventsSink, error) {
+	switch config.Mode {
+	case "":
+		config.Mode = CloudEventsStructured
+	case CloudEventsStructured:
+	case CloudEventsBinary:
+		if config.Batch {
+			return nil, fmt.Errorf("batching is not supported in %s mode", CloudEventsBinary)
+		}
+	default:
+		return nil, fmt.Errorf("unknown cloud events mode %s", config.Mode)
+	}
+
+	webhook, err := NewWebhookSink(config.WebhookSinkConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &CloudEventsSink{
+		webhook: webhook,
+		mode:    config.Mode,
+		batch:   config.Batch,
+	}, nil
+}
+
+// Write posts the results as cloud events, implements domain.PolicyValidationSink
+func (s *CloudEventsSink) Write(ctx context.Context, results []domain.PolicyValidation) error {
+	events := make([]domain.CloudEvent, 0, len(results))
+	for _, result := range results {
+		event, err := domain.NewCloudEventFromPolicyValidation(result)
+		if err != nil {
+			return fmt.Errorf("failed to convert result %s to cloud event: %w", result.ID, err)
+		}
+		events = append(events, event)
+	}
+
+	if s.batch {
+		body, err := json.Marshal(events)
+		if err != nil {
+			return fmt.Errorf("failed to marshal cloud events: %w", err)
+		}
+		return s.webhook.post(ctx, body, map[string]string{"Content-Type": cloudEventsBatchContentType})
+	}
+
+	var errs error
+	for _, event := range events {
+		var err error
+		if s.mode == CloudEventsBinary {
+			err = s.writeBinary(ctx, event)
+		} else {
+			err = s.writeStructured(ctx, event)
+		}
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("failed to post cloud event %s: %w", event.ID, err))
+		}
+	}
+	return errs
+}
+
+func (s *CloudEventsSink) writeStructured(ctx context.Context, event domain.CloudEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.webhook.post(ctx, body, map[string]string{"Content-Type": cloudEventsContentType})
+}
+
+func (s *CloudEventsSink) writeBinary(ctx context.Context, event domain.CloudEvent) error {
+	headers := map[string]string{
+		"Content-Type":   event.DataContentType,
+		"ce-specversion": event.SpecVersion,
+		"ce-id":          event.ID,
+		"ce-source":      event.Source,
+		"ce-type":        event.Type,
+		"ce-time":        event.Time.Format(time.RFC3339Nano),
+	}
+	if event.Subject != "" {
+		headers["ce-subject"] = event.Subject
+	}
+	return s.webhook.post(ctx, event.Data, headers)
+}
@@ -0,0 +1,115 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudEventsSink(t *testing.T) {
+	results := []domain.PolicyValidation{
+		{
+			ID:     "result-1",
+			Status: domain.PolicyValidationStatusViolating,
+			Entity: domain.Entity{Kind: "Deployment", Name: "nginx", Namespace: "default"},
+		},
+		{
+			ID:     "result-2",
+			Status: domain.PolicyValidationStatusCompliant,
+			Entity: domain.Entity{Kind: "Deployment", Name: "nginx", Namespace: "default"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		mode     CloudEventsMode
+		batch    bool
+		requests int
+		check    func(assert *require.Assertions, r *http.Request, body []byte)
+	}{
+		{
+			name:     "structured",
+			mode:     CloudEventsStructured,
+			requests: 2,
+			check: func(assert *require.Assertions, r *http.Request, body []byte) {
+				assert.Equal(cloudEventsContentType, r.Header.Get("Content-Type"))
+				var event domain.CloudEvent
+				assert.Nil(json.Unmarshal(body, &event))
+				result, err := domain.NewPolicyValidationFromCloudEvent(event)
+				assert.Nil(err)
+				assert.Contains([]string{"result-1", "result-2"}, result.ID)
+			},
+		},
+		{
+			name:     "structured batch",
+			mode:     CloudEventsStructured,
+			batch:    true,
+			requests: 1,
+			check: func(assert *require.Assertions, r *http.Request, body []byte) {
+				assert.Equal(cloudEventsBatchContentType, r.Header.Get("Content-Type"))
+				var events []domain.CloudEvent
+				assert.Nil(json.Unmarshal(body, &events))
+				assert.Len(events, 2)
+				assert.Equal(domain.CloudEventTypeViolation, events[0].Type)
+				assert.Equal(domain.CloudEventTypeCompliance, events[1].Type)
+			},
+		},
+		{
+			name:     "binary",
+			mode:     CloudEventsBinary,
+			requests: 2,
+			check: func(assert *require.Assertions, r *http.Request, body []byte) {
+				assert.Equal(domain.CloudEventDataContentType, r.Header.Get("Content-Type"))
+				assert.Equal(domain.CloudEventsSpecVersion, r.Header.Get("ce-specversion"))
+				assert.Equal("Deployment/default/nginx", r.Header.Get("ce-subject"))
+				var result domain.PolicyValidation
+				assert.Nil(json.Unmarshal(body, &result))
+				assert.Equal(r.Header.Get("ce-id"), result.ID)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := require.New(t)
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				body, err := ioutil.ReadAll(r.Body)
+				assert.Nil(err)
+				tt.check(assert, r, body)
+			}))
+			defer server.Close()
+
+			s, err := NewCloudEventsSink(CloudEventsSinkConfig{
+				WebhookSinkConfig: WebhookSinkConfig{URL: server.URL},
+				Mode:              tt.mode,
+				Batch:             tt.batch,
+			})
+			assert.Nil(err)
+			assert.Nil(s.Write(context.Background(), results))
+			assert.Equal(tt.requests, requests)
+		})
+	}
+}
+
+func TestNewCloudEventsSink_InvalidConfig(t *testing.T) {
+	assert := require.New(t)
+	_, err := NewCloudEventsSink(CloudEventsSinkConfig{
+		WebhookSinkConfig: WebhookSinkConfig{URL: "http://localhost"},
+		Mode:              CloudEventsBinary,
+		Batch:             true,
+	})
+	assert.Error(err)
+	_, err = NewCloudEventsSink(CloudEventsSinkConfig{
+		WebhookSinkConfig: WebhookSinkConfig{URL: "http://localhost"},
+		Mode:              "unknown",
+	})
+	assert.Error(err)
+}
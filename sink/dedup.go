@@ -0,0 +1,180 @@
+package sink
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"github.com/MagalixTechnologies/uuid-go"
+)
+
+const (
+	defaultDedupTTL       = time.Hour
+	defaultDedupRetention = 24 * time.Hour
+)
+
+// DedupSinkConfig configures a DedupSink
+type DedupSinkConfig struct {
+	// TTL is the period an identical result is suppressed for after being written
+	TTL time.Duration
+	// Retention is how long the state of a policy and entity pair is kept after it was last seen,
+	// it should be longer than the audit interval for resolved records to be emitted
+	Retention time.Duration
+}
+
+type dedupEntry struct {
+	fingerprint string
+	status      string
+	writtenAt   time.Time
+	seenAt      time.Time
+}
+
+// DedupSink suppresses results identical to ones written within the TTL and writes
+// a resolved record when a policy and entity pair turns from violating to compliant
+type DedupSink struct {
+	sink    domain.PolicyValidationSink
+	config  DedupSinkConfig
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[string]dedupEntry
+}
+
+// NewDedupSink returns a deduplicating sink wrapping the given sink
+func NewDedupSink(sink domain.PolicyValidationSink, config DedupSinkConfig) *DedupSink {
+	if config.TTL <= 0 {
+		config.TTL = defaultDedupTTL
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaultDedupRetention
+	}
+	if config.Retention < config.TTL {
+		config.Retention = config.TTL
+	}
+	return &DedupSink{
+		sink:    sink,
+		config:  config,
+		now:     time.Now,
+		entries: make(map[string]dedupEntry),
+	}
+}
+
+// Write writes the results that were not written recently, implements domain.PolicyValidationSink
+func (s *DedupSink) Write(ctx context.Context, results []domain.PolicyValidation) error {
+	now := s.now()
+	updates := make(map[string]dedupEntry)
+	var written []domain.PolicyValidation
+
+	s.mu.Lock()
+	s.prune(now)
+	for _, result := range results {
+		key := pairKey(result)
+		entry, ok := updates[key]
+		if !ok {
+			entry, ok = s.entries[key]
+		}
+		fingerprint := Fingerprint(result)
+
+		switch {
+		case ok && entry.status == domain.PolicyValidationStatusViolating && result.Status == domain.PolicyValidationStatusCompliant:
+			written = append(written, newResolvedResult(result))
+		case ok && entry.fingerprint == fingerprint && now.Sub(entry.writtenAt) < s.config.TTL:
+			entry.seenAt = now
+			updates[key] = entry
+			continue
+		default:
+			written = append(written, result)
+		}
+		updates[key] = dedupEntry{
+			fingerprint: fingerprint,
+			status:      result.Status,
+			writtenAt:   now,
+			seenAt:      now,
+		}
+	}
+	s.mu.Unlock()
+
+	if len(written) > 0 {
+		if err := s.sink.Write(ctx, written); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	for key, entry := range updates {
+		s.entries[key] = entry
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// prune removes the pairs not seen within the retention period
+func (s *DedupSink) prune(now time.Time) {
+	for key, entry := range s.entries {
+		if now.Sub(entry.seenAt) > s.config.Retention {
+			delete(s.entries, key)
+		}
+	}
+}
+
+func newResolvedResult(result domain.PolicyValidation) domain.PolicyValidation {
+	result.ID = uuid.NewV4().String()
+	result.Status = domain.PolicyValidationStatusResolved
+	result.Message = fmt.Sprintf(
+		"%s resolved in %s %s",
+		result.Policy.Name,
+		strings.ToLower(result.Entity.Kind),
+		result.Entity.Name,
+	)
+	result.Occurrences = nil
+	return result
+}
+
+// pairKey identifies the policy and entity of a result
+func pairKey(result domain.PolicyValidation) string {
+	entity := result.Entity.ID
+	if entity == "" {
+		entity = strings.Join([]string{
+			result.Entity.APIVersion,
+			result.Entity.Kind,
+			result.Entity.Namespace,
+			result.Entity.Name,
+		}, "/")
+	}
+	return result.Policy.ID + "|" + entity
+}
+
+// Fingerprint returns a hash identifying a result by its policy, entity, entity version, status and occurrences
+func Fingerprint(result domain.PolicyValidation) string {
+	hash := sha256.New()
+	hash.Write([]byte(pairKey(result)))
+	hash.Write([]byte{0})
+	hash.Write([]byte(result.Status))
+	hash.Write([]byte{0})
+	if result.Entity.ResourceVersion != "" {
+		hash.Write([]byte(result.Entity.ResourceVersion))
+	} else if manifest, err := json.Marshal(result.Entity.Manifest); err == nil {
+		hash.Write(manifest)
+	}
+
+	occurrences := make([]string, 0, len(result.Occurrences))
+	for _, occurrence := range result.Occurrences {
+		var key string
+		if occurrence.ViolatingKey != nil {
+			key = *occurrence.ViolatingKey
+		}
+		occurrences = append(occurrences, key+"="+occurrence.Message)
+	}
+	sort.Strings(occurrences)
+	for _, occurrence := range occurrences {
+		hash.Write([]byte{0})
+		hash.Write([]byte(occurrence))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,124 @@
+package sink
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupSink(t *testing.T) {
+	assert := require.New(t)
+	backend := &fakeSink{}
+	s := NewDedupSink(backend, DedupSinkConfig{TTL: time.Minute, Retention: time.Hour})
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	entity := domain.Entity{ID: "entity-1", Kind: "Deployment", Name: "nginx", ResourceVersion: "1"}
+	policy := domain.Policy{ID: "policy-1", Name: "Missing owner label"}
+	violation := domain.PolicyValidation{
+		ID:          "violation-1",
+		Policy:      policy,
+		Entity:      entity,
+		Status:      domain.PolicyValidationStatusViolating,
+		Occurrences: []domain.Occurrence{{Message: "missing owner"}},
+	}
+	compliance := domain.PolicyValidation{
+		ID:     "compliance-1",
+		Policy: policy,
+		Entity: entity,
+		Status: domain.PolicyValidationStatusCompliant,
+	}
+	statuses := func() []string {
+		var statuses []string
+		for _, result := range backend.written() {
+			statuses = append(statuses, result.Status)
+		}
+		return statuses
+	}
+
+	ctx := context.Background()
+	assert.Nil(s.Write(ctx, []domain.PolicyValidation{violation}))
+	assert.Len(backend.written(), 1)
+
+	// repeated within ttl
+	now = now.Add(30 * time.Second)
+	assert.Nil(s.Write(ctx, []domain.PolicyValidation{violation}))
+	assert.Len(backend.written(), 1)
+
+	// different occurrences
+	changed := violation
+	changed.Occurrences = []domain.Occurrence{{Message: "missing owner"}, {Message: "missing team"}}
+	assert.Nil(s.Write(ctx, []domain.PolicyValidation{changed}))
+	assert.Len(backend.written(), 2)
+
+	// repeated after ttl
+	now = now.Add(2 * time.Minute)
+	assert.Nil(s.Write(ctx, []domain.PolicyValidation{changed}))
+	assert.Len(backend.written(), 3)
+
+	// resolved
+	now = now.Add(10 * time.Second)
+	assert.Nil(s.Write(ctx, []domain.PolicyValidation{compliance}))
+	written := backend.written()
+	assert.Len(written, 4)
+	assert.Equal(domain.PolicyValidationStatusResolved, written[3].Status)
+	assert.Equal("Missing owner label resolved in deployment nginx", written[3].Message)
+
+	// compliance repeated within ttl
+	assert.Nil(s.Write(ctx, []domain.PolicyValidation{compliance}))
+	assert.Equal([]string{
+		domain.PolicyValidationStatusViolating,
+		domain.PolicyValidationStatusViolating,
+		domain.PolicyValidationStatusViolating,
+		domain.PolicyValidationStatusResolved,
+	}, statuses())
+
+	// state expired after retention
+	now = now.Add(2 * time.Hour)
+	assert.Nil(s.Write(ctx, []domain.PolicyValidation{compliance}))
+	assert.Equal(domain.PolicyValidationStatusCompliant, backend.written()[4].Status)
+}
+
+func TestDedupSink_WriteFailure(t *testing.T) {
+	assert := require.New(t)
+	backend := &fakeSink{failures: 1}
+	s := NewDedupSink(backend, DedupSinkConfig{})
+	violation := domain.PolicyValidation{
+		Policy: domain.Policy{ID: "policy-1"},
+		Entity: domain.Entity{Kind: "Deployment", Name: "nginx"},
+		Status: domain.PolicyValidationStatusViolating,
+	}
+
+	assert.Error(s.Write(context.Background(), []domain.PolicyValidation{violation}))
+	assert.Nil(s.Write(context.Background(), []domain.PolicyValidation{violation}))
+	assert.Len(backend.written(), 1)
+}
+
+func TestFingerprint(t *testing.T) {
+	assert := require.New(t)
+	key1, key2 := "spec.replicas", "metadata.labels"
+	result := domain.PolicyValidation{
+		Policy: domain.Policy{ID: "policy-1"},
+		Entity: domain.Entity{ID: "entity-1", Manifest: map[string]interface{}{"kind": "Deployment"}},
+		Status: domain.PolicyValidationStatusViolating,
+		Occurrences: []domain.Occurrence{
+			{Message: "a", ViolatingKey: &key1},
+			{Message: "b", ViolatingKey: &key2},
+		},
+	}
+	reordered := result
+	reordered.Occurrences = []domain.Occurrence{result.Occurrences[1], result.Occurrences[0]}
+	assert.Equal(Fingerprint(result), Fingerprint(reordered))
+
+	changedManifest := result
+	changedManifest.Entity.Manifest = map[string]interface{}{"kind": "StatefulSet"}
+	assert.NotEqual(Fingerprint(result), Fingerprint(changedManifest))
+
+	otherPolicy := result
+	otherPolicy.Policy.ID = "policy-2"
+	assert.NotEqual(Fingerprint(result), Fingerprint(otherPolicy))
+	assert.Len(Fingerprint(result), 64)
+}
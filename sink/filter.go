@@ -0,0 +1,89 @@
+package sink
+
+import (
+	"context"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+)
+
+// Filter selects the results a FilterSink passes to its wrapped sink.
+// Values of the same field are ORed while different fields are ANDed, empty fields match everything.
+type Filter struct {
+	Statuses   []string
+	Severities []string
+	Categories []string
+	Tags       []string
+	Standards  []string
+	// PolicySet when set, the result's policy must also match it
+	PolicySet *domain.PolicySet
+}
+
+// FilterSink writes only the results matching its filter to the wrapped sink
+type FilterSink struct {
+	sink       domain.PolicyValidationSink
+	filter     Filter
+	policySets []domain.PolicySet
+}
+
+// NewFilterSink returns a sink writing the results matching filter to sink
+func NewFilterSink(sink domain.PolicyValidationSink, filter Filter) *FilterSink {
+	// each policy field is matched by a dedicated policy set so that fields are ANDed
+	var policySets []domain.PolicySet
+	if len(filter.Severities) > 0 {
+		policySets = append(policySets, domain.PolicySet{Filters: domain.PolicySetFilters{Severities: filter.Severities}})
+	}
+	if len(filter.Categories) > 0 {
+		policySets = append(policySets, domain.PolicySet{Filters: domain.PolicySetFilters{Categories: filter.Categories}})
+	}
+	if len(filter.Tags) > 0 {
+		policySets = append(policySets, domain.PolicySet{Filters: domain.PolicySetFilters{Tags: filter.Tags}})
+	}
+	if len(filter.Standards) > 0 {
+		policySets = append(policySets, domain.PolicySet{Filters: domain.PolicySetFilters{Standards: filter.Standards}})
+	}
+	if filter.PolicySet != nil {
+		policySets = append(policySets, *filter.PolicySet)
+	}
+	return &FilterSink{
+		sink:       sink,
+		filter:     filter,
+		policySets: policySets,
+	}
+}
+
+// Match checks if the result matches the sink filter
+func (s *FilterSink) Match(result domain.PolicyValidation) bool {
+	if len(s.filter.Statuses) > 0 {
+		var found bool
+		for _, status := range s.filter.Statuses {
+			if result.Status == status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for i := range s.policySets {
+		if !s.policySets[i].Match(result.Policy) {
+			return false
+		}
+	}
+	return true
+}
+
+// Write writes the matching results to the wrapped sink, implements domain.PolicyValidationSink
+func (s *FilterSink) Write(ctx context.Context, results []domain.PolicyValidation) error {
+	var matched []domain.PolicyValidation
+	for _, result := range results {
+		if s.Match(result) {
+			matched = append(matched, result)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	return s.sink.Write(ctx, matched)
+}
@@ -0,0 +1,96 @@
+package sink
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterSink(t *testing.T) {
+	highViolation := domain.PolicyValidation{
+		ID:     "high-violation",
+		Status: domain.PolicyValidationStatusViolating,
+		Policy: domain.Policy{
+			ID:        "policy-1",
+			Severity:  "high",
+			Category:  "security",
+			Tags:      []string{"pci"},
+			Standards: []domain.PolicyStandard{{ID: "pci-dss"}},
+		},
+	}
+	lowViolation := domain.PolicyValidation{
+		ID:     "low-violation",
+		Status: domain.PolicyValidationStatusViolating,
+		Policy: domain.Policy{ID: "policy-2", Severity: "low", Category: "reliability"},
+	}
+	highCompliance := domain.PolicyValidation{
+		ID:     "high-compliance",
+		Status: domain.PolicyValidationStatusCompliant,
+		Policy: domain.Policy{ID: "policy-1", Severity: "high", Category: "security"},
+	}
+	results := []domain.PolicyValidation{highViolation, lowViolation, highCompliance}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{
+			name:   "empty filter",
+			filter: Filter{},
+			want:   []string{"high-violation", "low-violation", "high-compliance"},
+		},
+		{
+			name: "status and severity",
+			filter: Filter{
+				Statuses:   []string{domain.PolicyValidationStatusViolating},
+				Severities: []string{"high", "critical"},
+			},
+			want: []string{"high-violation"},
+		},
+		{
+			name:   "category",
+			filter: Filter{Categories: []string{"security"}},
+			want:   []string{"high-violation", "high-compliance"},
+		},
+		{
+			name: "tags and standards",
+			filter: Filter{
+				Tags:      []string{"pci"},
+				Standards: []string{"pci-dss"},
+			},
+			want: []string{"high-violation"},
+		},
+		{
+			name: "policy set",
+			filter: Filter{
+				PolicySet: &domain.PolicySet{Filters: domain.PolicySetFilters{IDs: []string{"policy-2"}}},
+			},
+			want: []string{"low-violation"},
+		},
+		{
+			name:   "no match",
+			filter: Filter{Severities: []string{"critical"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := require.New(t)
+			backend := &fakeSink{}
+			err := NewFilterSink(backend, tt.filter).Write(context.Background(), results)
+			assert.Nil(err)
+
+			var got []string
+			for _, result := range backend.written() {
+				got = append(got, result.ID)
+			}
+			assert.Equal(tt.want, got)
+			if len(tt.want) == 0 {
+				assert.Empty(backend.batches)
+			}
+		})
+	}
+}
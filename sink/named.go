@@ -0,0 +1,28 @@
+package sink
+
+import (
+	"context"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+)
+
+// NamedSink gives a name to the wrapped sink, the name identifies the sink in delivery statuses and metrics
+type NamedSink struct {
+	sink domain.PolicyValidationSink
+	name string
+}
+
+// NewNamedSink returns a sink writing to sink and identified by name, implements domain.NamedSink
+func NewNamedSink(name string, sink domain.PolicyValidationSink) *NamedSink {
+	return &NamedSink{sink: sink, name: name}
+}
+
+// Name returns the name of the sink, implements domain.NamedSink
+func (s *NamedSink) Name() string {
+	return s.name
+}
+
+// Write writes the results to the wrapped sink, implements domain.PolicyValidationSink
+func (s *NamedSink) Write(ctx context.Context, results []domain.PolicyValidation) error {
+	return s.sink.Write(ctx, results)
+}
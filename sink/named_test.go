@@ -0,0 +1,20 @@
+package sink
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamedSink(t *testing.T) {
+	assert := require.New(t)
+	backend := &fakeSink{}
+	var named domain.PolicyValidationSink = NewNamedSink("audit-webhook", backend)
+
+	assert.Implements((*domain.NamedSink)(nil), named)
+	assert.Equal("audit-webhook", named.(domain.NamedSink).Name())
+	assert.Nil(named.Write(context.Background(), newResults(2)))
+	assert.Len(backend.written(), 2)
+}
@@ -0,0 +1,160 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+)
+
+const (
+	// SignatureHeader holds the HMAC-SHA256 signature of the request body as sha256=<hex>
+	SignatureHeader = "X-Signature-256"
+
+	defaultWebhookTimeout = 10 * time.Second
+	maxErrorBodySize      = 1024
+)
+
+// TLSOptions configures the TLS connection to the webhook
+type TLSOptions struct {
+	// CAFile is a PEM file of certificate authorities used to verify the server certificate
+	CAFile string
+	// CertFile and KeyFile are a PEM client certificate and key used for mutual TLS
+	CertFile string
+	KeyFile  string
+	// InsecureSkipVerify disables verifying the server certificate
+	InsecureSkipVerify bool
+}
+
+// WebhookSinkConfig configures a WebhookSink
+type WebhookSinkConfig struct {
+	// URL is the endpoint results are posted to
+	URL string
+	// Headers are added to every request
+	Headers map[string]string
+	// HMACSecret when set, requests are signed and the signature is sent in the SignatureHeader
+	HMACSecret []byte
+	// TLS configures the connection to https endpoints
+	TLS TLSOptions
+	// Timeout is the maximum duration of a request
+	Timeout time.Duration
+}
+
+// WebhookSink posts results as a JSON array to an HTTP endpoint
+type WebhookSink struct {
+	config WebhookSinkConfig
+	client *http.Client
+}
+
+// NewWebhookSink returns a sink posting results to the configured URL
+func NewWebhookSink(config WebhookSinkConfig) (*WebhookSink, error) {
+	endpoint, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook url: %w", err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("invalid webhook url %s: scheme must be http or https", config.URL)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultWebhookTimeout
+	}
+
+	tlsConfig, err := newTLSConfig(config.TLS)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &WebhookSink{
+		config: config,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   config.Timeout,
+		},
+	}, nil
+}
+
+// Write posts the results to the webhook, implements domain.PolicyValidationSink
+func (s *WebhookSink) Write(ctx context.Context, results []domain.PolicyValidation) error {
+	body, err := json.Marshal(results)
+	if err != nil {
+		return fmt.Errorf("failed to marshal results: %w", err)
+	}
+	return s.post(ctx, body, map[string]string{"Content-Type": "application/json"})
+}
+
+// post sends body to the webhook with the given headers in addition to the configured ones
+func (s *WebhookSink) post(ctx context.Context, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	for key, value := range s.config.Headers {
+		req.Header.Set(key, value)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if len(s.config.HMACSecret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(s.config.HMACSecret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post results to webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return fmt.Errorf("webhook responded with status %d: %s", resp.StatusCode, string(respBody))
+	}
+	// drain the body so the connection can be reused
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+// Sign returns the HMAC-SHA256 signature of body as sent in the SignatureHeader
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newTLSConfig(options TLSOptions) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: options.InsecureSkipVerify,
+	}
+	if options.CAFile != "" {
+		ca, err := ioutil.ReadFile(options.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in ca file %s", options.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	if options.CertFile != "" || options.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(options.CertFile, options.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
@@ -0,0 +1,93 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSink(t *testing.T) {
+	assert := require.New(t)
+	secret := []byte("secret")
+
+	var received []domain.PolicyValidation
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Nil(err)
+		assert.Equal(http.MethodPost, r.Method)
+		assert.Equal("application/json", r.Header.Get("Content-Type"))
+		assert.Equal("Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(Sign(secret, body), r.Header.Get(SignatureHeader))
+		assert.Nil(json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	s, err := NewWebhookSink(WebhookSinkConfig{
+		URL:        server.URL,
+		Headers:    map[string]string{"Authorization": "Bearer token"},
+		HMACSecret: secret,
+	})
+	assert.Nil(err)
+
+	err = s.Write(context.Background(), []domain.PolicyValidation{{ID: "result-1"}, {ID: "result-2"}})
+	assert.Nil(err)
+	assert.Len(received, 2)
+	assert.Equal("result-1", received[0].ID)
+}
+
+func TestWebhookSink_Errors(t *testing.T) {
+	assert := require.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+			return
+		}
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	s, err := NewWebhookSink(WebhookSinkConfig{URL: server.URL})
+	assert.Nil(err)
+	err = s.Write(context.Background(), []domain.PolicyValidation{{ID: "result-1"}})
+	assert.ErrorContains(err, "503")
+	assert.ErrorContains(err, "storage unavailable")
+
+	s, err = NewWebhookSink(WebhookSinkConfig{URL: server.URL + "/slow", Timeout: 20 * time.Millisecond})
+	assert.Nil(err)
+	assert.Error(s.Write(context.Background(), []domain.PolicyValidation{{ID: "result-1"}}))
+
+	_, err = NewWebhookSink(WebhookSinkConfig{URL: "ftp://example.com"})
+	assert.Error(err)
+}
+
+func TestWebhookSink_TLS(t *testing.T) {
+	assert := require.New(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	s, err := NewWebhookSink(WebhookSinkConfig{URL: server.URL})
+	assert.Nil(err)
+	assert.Error(s.Write(context.Background(), []domain.PolicyValidation{{ID: "result-1"}}))
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.Nil(ioutil.WriteFile(caFile, ca, 0600))
+
+	s, err = NewWebhookSink(WebhookSinkConfig{URL: server.URL, TLS: TLSOptions{CAFile: caFile}})
+	assert.Nil(err)
+	assert.Nil(s.Write(context.Background(), []domain.PolicyValidation{{ID: "result-1"}}))
+
+	s, err = NewWebhookSink(WebhookSinkConfig{URL: server.URL, TLS: TLSOptions{InsecureSkipVerify: true}})
+	assert.Nil(err)
+	assert.Nil(s.Write(context.Background(), []domain.PolicyValidation{{ID: "result-1"}}))
+}
@@ -2,8 +2,20 @@ package validation
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
 
+	"github.com/MagalixTechnologies/core/logger"
 	"github.com/MagalixTechnologies/policy-core/domain"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 func matchEntity(entity domain.Entity, policy domain.Policy) bool {
@@ -56,17 +68,146 @@ func matchEntity(entity domain.Entity, policy domain.Policy) bool {
 	return matchKind && matchNamespace && matchLabel
 }
 
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// writeToSinks writes the results to all sinks concurrently, each sink receives
+// a single write and is given at most timeout to finish when timeout is set
 func writeToSinks(
 	ctx context.Context,
+	tracer trace.Tracer,
 	resultsSinks []domain.PolicyValidationSink,
 	PolicyValidationSummary domain.PolicyValidationSummary,
-	writeCompliance bool) {
-	for _, resutsSink := range resultsSinks {
-		if len(PolicyValidationSummary.Violations) > 0 {
-			resutsSink.Write(ctx, PolicyValidationSummary.Violations)
+	writeCompliance bool,
+	timeout time.Duration) []domain.SinkDeliveryStatus {
+	results := make([]domain.PolicyValidation, 0, len(PolicyValidationSummary.Violations)+len(PolicyValidationSummary.Compliances))
+	results = append(results, PolicyValidationSummary.Violations...)
+	if writeCompliance {
+		results = append(results, PolicyValidationSummary.Compliances...)
+	}
+
+	statuses := make([]domain.SinkDeliveryStatus, len(resultsSinks))
+	for i, resutsSink := range resultsSinks {
+		statuses[i].Sink = sinkName(resutsSink)
+	}
+	if len(results) == 0 {
+		return statuses
+	}
+
+	var wg sync.WaitGroup
+	bound := make(chan struct{}, maxSinkWorkers)
+	for i := range resultsSinks {
+		bound <- struct{}{}
+		wg.Add(1)
+		go func(index int) {
+			defer func() {
+				<-bound
+				wg.Done()
+			}()
+
+			sinkCtx := ctx
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				sinkCtx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			status := &statuses[index]
+			sinkCtx, span := startSpan(sinkCtx, tracer, "WriteSink",
+				attribute.String("sink", status.Sink),
+				attribute.Int("results.count", len(results)),
+			)
+			defer span.End()
+
+			if err := writeToSink(sinkCtx, resultsSinks[index], results); err != nil {
+				recordSpanError(span, err)
+				status.Error = err
+				logger.Errorw("failed to write validation results to sink", "sink", status.Sink, "error", err)
+				return
+			}
+			status.Delivered = len(results)
+		}(i)
+	}
+	wg.Wait()
+	return statuses
+}
+
+// writeToSink writes results to the sink, it returns once the write returns or ctx is done
+func writeToSink(ctx context.Context, resultsSink domain.PolicyValidationSink, results []domain.PolicyValidation) error {
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- resultsSink.Write(ctx, results)
+	}()
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("sink write did not finish: %w", ctx.Err())
+	}
+}
+
+// gatekeeperInput builds the gatekeeper compliant input policies are evaluated with,
+// entities received in admission requests also carry the request details and old object
+func gatekeeperInput(entity domain.Entity, parameters map[string]interface{}) (map[string]interface{}, error) {
+	obj := unstructured.Unstructured{Object: entity.Manifest}
+	raw, err := json.Marshal(entity.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	review := admissionv1.AdmissionRequest{
+		Name: obj.GetName(),
+		Kind: metav1.GroupVersionKind{
+			Group:   gvk.Group,
+			Version: gvk.Version,
+			Kind:    gvk.Kind,
+		},
+		Object: runtime.RawExtension{Raw: raw},
+	}
+	if admission := entity.Admission; admission != nil {
+		review.UID = types.UID(admission.UID)
+		review.Namespace = entity.Namespace
+		review.Operation = admissionv1.Operation(admission.Operation)
+		review.UserInfo = admission.UserInfo
+		review.SubResource = admission.SubResource
+		review.DryRun = &admission.DryRun
+		if admission.OldObject != nil {
+			oldRaw, err := json.Marshal(admission.OldObject)
+			if err != nil {
+				return nil, err
+			}
+			review.OldObject = runtime.RawExtension{Raw: oldRaw}
 		}
-		if writeCompliance && len(PolicyValidationSummary.Compliances) > 0 {
-			resutsSink.Write(ctx, PolicyValidationSummary.Compliances)
+	}
+
+	// round trip the input so it holds plain json values
+	raw, err = json.Marshal(map[string]interface{}{
+		"review":     review,
+		"parameters": parameters,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var input map[string]interface{}
+	if err := json.Unmarshal(raw, &input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+// sinkName returns the name of the sink if it implements domain.NamedSink, its type name otherwise
+func sinkName(resultsSink domain.PolicyValidationSink) string {
+	if named, ok := resultsSink.(domain.NamedSink); ok {
+		if name := named.Name(); name != "" {
+			return name
 		}
 	}
+	return fmt.Sprintf("%T", resultsSink)
 }
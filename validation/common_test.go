@@ -0,0 +1,65 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+type funcSink func(ctx context.Context, results []domain.PolicyValidation) error
+
+func (f funcSink) Write(ctx context.Context, results []domain.PolicyValidation) error {
+	return f(ctx, results)
+}
+
+func TestWriteToSinks(t *testing.T) {
+	assert := require.New(t)
+	summary := domain.PolicyValidationSummary{
+		Violations:  []domain.PolicyValidation{{ID: "violation"}},
+		Compliances: []domain.PolicyValidation{{ID: "compliance"}},
+	}
+
+	var writes int32
+	countingSink := funcSink(func(ctx context.Context, results []domain.PolicyValidation) error {
+		atomic.AddInt32(&writes, 1)
+		return nil
+	})
+	slowSink := funcSink(func(ctx context.Context, results []domain.PolicyValidation) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	failingSink := funcSink(func(ctx context.Context, results []domain.PolicyValidation) error {
+		return fmt.Errorf("sink unavailable")
+	})
+
+	start := time.Now()
+	statuses := writeToSinks(
+		context.Background(),
+		nil,
+		[]domain.PolicyValidationSink{countingSink, slowSink, failingSink, countingSink},
+		summary,
+		true,
+		50*time.Millisecond,
+	)
+	assert.Less(int64(time.Since(start)), int64(500*time.Millisecond))
+
+	assert.Equal(int32(2), atomic.LoadInt32(&writes))
+	assert.Len(statuses, 4)
+	assert.Equal(2, statuses[0].Delivered)
+	assert.Nil(statuses[0].Error)
+	assert.ErrorIs(statuses[1].Error, context.DeadlineExceeded)
+	assert.Error(statuses[2].Error)
+	assert.Equal(2, statuses[3].Delivered)
+
+	statuses = writeToSinks(context.Background(), nil, []domain.PolicyValidationSink{countingSink}, summary, false, 0)
+	assert.Equal(1, statuses[0].Delivered)
+
+	statuses = writeToSinks(context.Background(), nil, []domain.PolicyValidationSink{countingSink}, domain.PolicyValidationSummary{}, true, 0)
+	assert.Equal(0, statuses[0].Delivered)
+	assert.Equal(int32(3), atomic.LoadInt32(&writes))
+}
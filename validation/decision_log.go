@@ -0,0 +1,77 @@
+package validation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	v1 "k8s.io/api/core/v1"
+)
+
+// PolicyDecision describes the evaluation of a single policy against an entity
+type PolicyDecision struct {
+	PolicyID   string                 `json:"policy_id"`
+	PolicyName string                 `json:"policy_name"`
+	GitCommit  string                 `json:"git_commit,omitempty"`
+	CodeHash   string                 `json:"code_hash"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Status     string                 `json:"status"`
+	Error      string                 `json:"error,omitempty"`
+	DurationMs float64                `json:"duration_ms"`
+}
+
+// DecisionLog records the policies evaluated by a single Validate call and their results
+type DecisionLog struct {
+	Timestamp  time.Time           `json:"timestamp"`
+	AccountID  string              `json:"account_id"`
+	ClusterID  string              `json:"cluster_id"`
+	Type       string              `json:"type"`
+	Trigger    string              `json:"trigger"`
+	Entity     *v1.ObjectReference `json:"entity"`
+	Policies   []PolicyDecision    `json:"policies"`
+	Error      string              `json:"error,omitempty"`
+	DurationMs float64             `json:"duration_ms"`
+}
+
+// DecisionLogger receives a decision log for every Validate call
+type DecisionLogger interface {
+	// Log records the decision log
+	Log(ctx context.Context, decision DecisionLog) error
+}
+
+// JSONDecisionLogger writes decision logs to a writer as JSON lines
+type JSONDecisionLogger struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSONDecisionLogger returns a decision logger writing to w
+func NewJSONDecisionLogger(w io.Writer) *JSONDecisionLogger {
+	return &JSONDecisionLogger{encoder: json.NewEncoder(w)}
+}
+
+// Log writes the decision log as a single JSON line, implements DecisionLogger
+func (l *JSONDecisionLogger) Log(ctx context.Context, decision DecisionLog) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.encoder.Encode(decision)
+}
+
+func newPolicyDecision(policy domain.Policy) PolicyDecision {
+	hash := sha256.Sum256([]byte(policy.Code))
+	return PolicyDecision{
+		PolicyID:   policy.ID,
+		PolicyName: policy.Name,
+		GitCommit:  policy.GitCommit,
+		CodeHash:   hex.EncodeToString(hash[:]),
+	}
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,93 @@
+package validation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"github.com/MagalixTechnologies/policy-core/domain/mock"
+	"github.com/MagalixTechnologies/policy-core/validation/testdata"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpaValidator_DecisionLog(t *testing.T) {
+	assert := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	entity, err := getEntityFromStringSpec(testdata.Entity)
+	assert.Nil(err)
+	replicaCount := testdata.Policies["replicaCount"]
+	replicaCount.GitCommit = "abc123"
+	imageTag := testdata.Policies["imageTag"]
+	imageTag.Targets = domain.PolicyTargets{Kinds: []string{"ReplicaSet"}}
+
+	policiesSource := mock.NewMockPoliciesSource(ctrl)
+	policiesSource.EXPECT().GetAll(gomock.Any()).
+		Times(1).Return([]domain.Policy{replicaCount, imageTag}, nil)
+	policiesSource.EXPECT().GetPolicyConfig(gomock.Any(), gomock.Any()).
+		Times(1).Return(&domain.PolicyConfig{
+		Config: map[string]domain.PolicyConfigConfig{
+			replicaCount.ID: {
+				Parameters: map[string]domain.PolicyConfigParameter{
+					"replica_count": {Value: 2, ConfigRef: "my-config"},
+				},
+			},
+		},
+	}, nil)
+
+	var buf bytes.Buffer
+	v := NewOPAValidator(policiesSource, false, "unit-test", "account", "cluster", false).
+		WithDecisionLogger(NewJSONDecisionLogger(&buf))
+	_, err = v.Validate(context.Background(), entity, "admission")
+	assert.Nil(err)
+
+	var decision DecisionLog
+	assert.Nil(json.Unmarshal(buf.Bytes(), &decision))
+	assert.Equal("admission", decision.Trigger)
+	assert.Equal("unit-test", decision.Type)
+	assert.Equal("account", decision.AccountID)
+	assert.Equal("nginx-deployment", decision.Entity.Name)
+	assert.Empty(decision.Error)
+
+	// policies not targeting the entity are not evaluated
+	assert.Len(decision.Policies, 1)
+	policy := decision.Policies[0]
+	assert.Equal(replicaCount.ID, policy.PolicyID)
+	assert.Equal("abc123", policy.GitCommit)
+	assert.Equal(newPolicyDecision(replicaCount).CodeHash, policy.CodeHash)
+	assert.Len(policy.CodeHash, 64)
+	assert.Equal(float64(2), policy.Parameters["replica_count"])
+	assert.Equal(domain.PolicyValidationStatusCompliant, policy.Status)
+}
+
+func TestOpaValidator_DecisionLogError(t *testing.T) {
+	assert := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	entity, err := getEntityFromStringSpec(testdata.Entity)
+	assert.Nil(err)
+
+	policiesSource := mock.NewMockPoliciesSource(ctrl)
+	policiesSource.EXPECT().GetAll(gomock.Any()).
+		Times(1).Return([]domain.Policy{testdata.Policies["badPolicyCode"]}, nil)
+	policiesSource.EXPECT().GetPolicyConfig(gomock.Any(), gomock.Any()).
+		Times(1).Return(nil, nil)
+
+	var buf bytes.Buffer
+	v := NewOPAValidator(policiesSource, false, "unit-test", "", "", false).
+		WithDecisionLogger(NewJSONDecisionLogger(&buf))
+	_, err = v.Validate(context.Background(), entity, "admission")
+	assert.Error(err)
+
+	var decision DecisionLog
+	assert.Nil(json.Unmarshal(buf.Bytes(), &decision))
+	assert.NotEmpty(decision.Error)
+	assert.Len(decision.Policies, 1)
+	assert.Equal(resultStatusError, decision.Policies[0].Status)
+	assert.NotEmpty(decision.Policies[0].Error)
+}
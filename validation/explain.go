@@ -0,0 +1,154 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/topdown"
+)
+
+// FiredRule is a rule of the policy that evaluated successfully
+type FiredRule struct {
+	// Name is the rule name
+	Name string `json:"name"`
+	// Location is the position of the rule in the policy code as row:col
+	Location string `json:"location"`
+	// Bindings holds the values of the rule variables
+	Bindings map[string]interface{} `json:"bindings"`
+}
+
+// Explanation describes how a policy evaluated an entity
+type Explanation struct {
+	Policy domain.Policy `json:"policy"`
+	// Parameters are the effective policy parameters after applying the policy config
+	Parameters map[string]interface{} `json:"parameters"`
+	// Input is the input the policy was evaluated with
+	Input map[string]interface{} `json:"input"`
+	// Violations are the values produced by the violation rule
+	Violations []interface{} `json:"violations"`
+	// FiredRules are the rules that evaluated successfully in evaluation order
+	FiredRules []FiredRule `json:"fired_rules"`
+	// Trace is the full OPA evaluation trace
+	Trace string `json:"trace"`
+}
+
+// Explain evaluates a single policy against the entity and returns how it was evaluated.
+// Unlike Validate, results are not written to sinks and the entity is not mutated.
+func (v *OpaValidator) Explain(ctx context.Context, entity domain.Entity, policyID string) (*Explanation, error) {
+	policies, err := v.policiesSource.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get policies from source: %w", err)
+	}
+
+	var policy *domain.Policy
+	for i := range policies {
+		if policies[i].ID == policyID {
+			policy = &policies[i]
+			break
+		}
+	}
+	if policy == nil {
+		return nil, fmt.Errorf("policy %s is not found", policyID)
+	}
+
+	config, _, err := v.getPolicyConfig(ctx, entity)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get policy config from source: %w", err)
+	}
+
+	effective := applyPolicyConfig(*policy, config)
+	parameters, err := effective.ValidateParameters(effective.GetParametersMap())
+	if err != nil {
+		return nil, err
+	}
+	explanation := &Explanation{
+		Policy:     effective,
+		Parameters: parameters,
+	}
+
+	module, err := ast.ParseModule(policy.ID, policy.Code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy %s: %w", policy.ID, err)
+	}
+	if module == nil {
+		return nil, fmt.Errorf("failed to parse policy %s: empty content", policy.ID)
+	}
+
+	explanation.Input, err = gatekeeperInput(entity, explanation.Parameters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build input of policy %s: %w", policy.ID, err)
+	}
+
+	tracer := topdown.NewBufferTracer()
+	query := module.Package.Path.Append(ast.StringTerm(PolicyQuery))
+	rs, err := rego.New(
+		rego.Query(query.String()),
+		rego.ParsedModule(module),
+		rego.Input(explanation.Input),
+		rego.QueryTracer(tracer),
+	).Eval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to evaluate resource against policy. policy id: %s. %w", policy.ID, err)
+	}
+
+	for _, result := range rs {
+		for _, expr := range result.Expressions {
+			if values, ok := expr.Value.([]interface{}); ok {
+				explanation.Violations = append(explanation.Violations, values...)
+			} else if expr.Value != nil {
+				explanation.Violations = append(explanation.Violations, expr.Value)
+			}
+		}
+	}
+
+	for _, event := range *tracer {
+		if event.Op != topdown.ExitOp {
+			continue
+		}
+		rule, ok := event.Node.(*ast.Rule)
+		if !ok {
+			continue
+		}
+		explanation.FiredRules = append(explanation.FiredRules, newFiredRule(rule, event))
+	}
+
+	var trace strings.Builder
+	topdown.PrettyTraceWithLocation(&trace, *tracer)
+	explanation.Trace = trace.String()
+
+	return explanation, nil
+}
+
+func newFiredRule(rule *ast.Rule, event *topdown.Event) FiredRule {
+	fired := FiredRule{
+		Name:     rule.Head.Name.String(),
+		Bindings: map[string]interface{}{},
+	}
+	if rule.Location != nil {
+		fired.Location = fmt.Sprintf("%d:%d", rule.Location.Row, rule.Location.Col)
+	}
+	if event.Locals != nil {
+		event.Locals.Iter(func(key, value ast.Value) bool {
+			name, ok := key.(ast.Var)
+			if !ok {
+				return false
+			}
+			// variables declared with := are renamed by the compiler, use their original names
+			if metadata, ok := event.LocalMetadata[name]; ok {
+				name = metadata.Name
+			}
+			if name.IsGenerated() || name.IsWildcard() {
+				return false
+			}
+			if binding, err := ast.JSON(value); err == nil {
+				fired.Bindings[string(name)] = binding
+			}
+			return false
+		})
+	}
+	return fired
+}
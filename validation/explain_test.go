@@ -0,0 +1,92 @@
+package validation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"github.com/MagalixTechnologies/policy-core/domain/mock"
+	"github.com/MagalixTechnologies/policy-core/validation/testdata"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpaValidator_Explain(t *testing.T) {
+	assert := require.New(t)
+	entity, err := getEntityFromStringSpec(testdata.Entity)
+	assert.Nil(err)
+	compliantEntity, err := getEntityFromStringSpec(testdata.CompliantEntity)
+	assert.Nil(err)
+
+	missingOwner := testdata.Policies["missingOwner"]
+	badPolicy := testdata.Policies["badPolicyCode"]
+
+	tests := []struct {
+		name       string
+		entity     domain.Entity
+		policyID   string
+		violations int
+		wantErr    bool
+	}{
+		{
+			name:       "violating entity",
+			entity:     entity,
+			policyID:   missingOwner.ID,
+			violations: 1,
+		},
+		{
+			name:     "compliant entity",
+			entity:   compliantEntity,
+			policyID: missingOwner.ID,
+		},
+		{
+			name:     "unknown policy",
+			entity:   entity,
+			policyID: "unknown",
+			wantErr:  true,
+		},
+		{
+			name:     "bad policy code",
+			entity:   entity,
+			policyID: badPolicy.ID,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := require.New(t)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			policiesSource := mock.NewMockPoliciesSource(ctrl)
+			policiesSource.EXPECT().GetAll(gomock.Any()).
+				Times(1).Return([]domain.Policy{missingOwner, badPolicy}, nil)
+			policiesSource.EXPECT().GetPolicyConfig(gomock.Any(), gomock.Any()).
+				MaxTimes(1).Return(nil, nil)
+
+			v := NewOPAValidator(policiesSource, false, "unit-test", "", "", false)
+			explanation, err := v.Explain(context.Background(), tt.entity, tt.policyID)
+			if tt.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.Nil(err)
+			assert.Equal(tt.policyID, explanation.Policy.ID)
+			assert.Len(explanation.Violations, tt.violations)
+			assert.NotEmpty(explanation.Trace)
+			assert.Contains(explanation.Parameters, "exclude_namespace")
+
+			review := explanation.Input["review"].(map[string]interface{})
+			assert.Equal("nginx-deployment", review["object"].(map[string]interface{})["metadata"].(map[string]interface{})["name"])
+
+			var violationFired bool
+			for _, rule := range explanation.FiredRules {
+				if rule.Name == PolicyQuery {
+					violationFired = true
+					assert.Equal("owner", rule.Bindings["label"])
+				}
+			}
+			assert.Equal(tt.violations > 0, violationFired)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package validation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "policy_core"
+
+	resultStatusError = "Error"
+	sinkStatusSuccess = "success"
+	sinkStatusFailure = "failure"
+)
+
+// Metrics holds the prometheus metrics recorded by the validator, a nil Metrics records nothing
+type Metrics struct {
+	validationDuration *prometheus.HistogramVec
+	evaluationDuration *prometheus.HistogramVec
+	results            *prometheus.CounterVec
+	mutations          *prometheus.CounterVec
+	sinkWrites         *prometheus.CounterVec
+}
+
+// NewMetrics creates the validator metrics and registers them on the given registerer
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		validationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "validation_duration_seconds",
+			Help:      "Duration of validating an entity against all policies.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"kind", "trigger"}),
+		evaluationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "policy_evaluation_duration_seconds",
+			Help:      "Duration of evaluating a single policy against an entity.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"policy_id"}),
+		results: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "validation_results_total",
+			Help:      "Number of policy validation results by status.",
+		}, []string{"policy_id", "severity", "trigger", "status"}),
+		mutations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "mutations_total",
+			Help:      "Number of violations fixed by mutating the entity.",
+		}, []string{"policy_id"}),
+		sinkWrites: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "sink_writes_total",
+			Help:      "Number of writes to results sinks by status.",
+		}, []string{"sink", "status"}),
+	}
+
+	collectors := []prometheus.Collector{
+		m.validationDuration,
+		m.evaluationDuration,
+		m.results,
+		m.mutations,
+		m.sinkWrites,
+	}
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			return nil, fmt.Errorf("failed to register validation metrics: %w", err)
+		}
+	}
+	return m, nil
+}
+
+func (m *Metrics) observeValidation(entity domain.Entity, trigger string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.validationDuration.WithLabelValues(entity.Kind, trigger).Observe(duration.Seconds())
+}
+
+func (m *Metrics) observeEvaluation(policy domain.Policy, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.evaluationDuration.WithLabelValues(policy.ID).Observe(duration.Seconds())
+}
+
+func (m *Metrics) countResult(policy domain.Policy, trigger string, status string) {
+	if m == nil {
+		return
+	}
+	m.results.WithLabelValues(policy.ID, policy.Severity, trigger, status).Inc()
+}
+
+func (m *Metrics) countMutation(policy domain.Policy) {
+	if m == nil {
+		return
+	}
+	m.mutations.WithLabelValues(policy.ID).Inc()
+}
+
+func (m *Metrics) countSinkWrites(statuses []domain.SinkDeliveryStatus) {
+	if m == nil {
+		return
+	}
+	for _, status := range statuses {
+		if status.Error != nil {
+			m.sinkWrites.WithLabelValues(status.Sink, sinkStatusFailure).Inc()
+		} else if status.Delivered > 0 {
+			m.sinkWrites.WithLabelValues(status.Sink, sinkStatusSuccess).Inc()
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"github.com/MagalixTechnologies/policy-core/domain/mock"
+	"github.com/MagalixTechnologies/policy-core/validation/testdata"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpaValidator_Metrics(t *testing.T) {
+	assert := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	entity, err := getEntityFromStringSpec(testdata.Entity)
+	assert.Nil(err)
+	imageTag := testdata.Policies["imageTag"]
+	missingOwner := testdata.Policies["missingOwner"]
+	replicaCount := testdata.Policies["replicaCount"]
+
+	policiesSource := mock.NewMockPoliciesSource(ctrl)
+	policiesSource.EXPECT().GetAll(gomock.Any()).
+		Times(1).Return([]domain.Policy{imageTag, missingOwner, replicaCount}, nil)
+	policiesSource.EXPECT().GetPolicyConfig(gomock.Any(), gomock.Any()).
+		Times(1).Return(nil, nil)
+	sink := mock.NewMockPolicyValidationSink(ctrl)
+	sink.EXPECT().Write(gomock.Any(), gomock.Any()).
+		Times(1).Return(fmt.Errorf("sink unavailable"))
+
+	registry := prometheus.NewRegistry()
+	metrics, err := NewMetrics(registry)
+	assert.Nil(err)
+	_, err = NewMetrics(registry)
+	assert.Error(err, "registering metrics twice should fail")
+
+	v := NewOPAValidator(policiesSource, true, "unit-test", "", "", true, sink).WithMetrics(metrics)
+	_, err = v.Validate(context.Background(), entity, "admission")
+	assert.Nil(err)
+
+	assert.Equal(1, testutil.CollectAndCount(metrics.validationDuration))
+	assert.Equal(3, testutil.CollectAndCount(metrics.evaluationDuration))
+	assert.Equal(float64(1), testutil.ToFloat64(
+		metrics.results.WithLabelValues(imageTag.ID, imageTag.Severity, "admission", domain.PolicyValidationStatusViolating)))
+	assert.Equal(float64(1), testutil.ToFloat64(
+		metrics.results.WithLabelValues(missingOwner.ID, missingOwner.Severity, "admission", domain.PolicyValidationStatusViolating)))
+	assert.Equal(float64(1), testutil.ToFloat64(
+		metrics.results.WithLabelValues(replicaCount.ID, replicaCount.Severity, "admission", domain.PolicyValidationStatusViolating)))
+	assert.Equal(float64(1), testutil.ToFloat64(metrics.mutations.WithLabelValues(missingOwner.ID)))
+	assert.Equal(float64(1), testutil.ToFloat64(
+		metrics.sinkWrites.WithLabelValues("*mock.MockPolicyValidationSink", sinkStatusFailure)))
+}
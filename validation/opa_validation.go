@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -13,11 +14,14 @@ import (
 	"github.com/MagalixTechnologies/policy-core/domain"
 	"github.com/MagalixTechnologies/uuid-go"
 	multierror "github.com/hashicorp/go-multierror"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	PolicyQuery = "violation"
-	maxWorkers  = 25
+	PolicyQuery    = "violation"
+	maxWorkers     = 25
+	maxSinkWorkers = 10
 )
 
 type OpaValidator struct {
@@ -28,6 +32,13 @@ type OpaValidator struct {
 	accountID       string
 	clusterID       string
 	mutate          bool
+	failOnSinkError bool
+	sinkTimeout     time.Duration
+	metrics         *Metrics
+	tracer          trace.Tracer
+	decisionLogger  DecisionLogger
+	mode            string
+	policySets      []domain.PolicySet
 }
 
 // NewOPAValidator returns an opa validator to validate entities
@@ -51,17 +62,86 @@ func NewOPAValidator(
 	}
 }
 
-// Validate validate policies using opa library, implements validation.Validator
-func (v *OpaValidator) Validate(ctx context.Context, entity domain.Entity, trigger string) (*domain.PolicyValidationSummary, error) {
-	policies, err := v.policiesSource.GetAll(ctx)
+// WithFailOnSinkError makes Validate return an error when a sink fails to write the results,
+// the validation summary is still returned along with the error
+func (v *OpaValidator) WithFailOnSinkError(fail bool) *OpaValidator {
+	v.failOnSinkError = fail
+	return v
+}
+
+// WithSinkTimeout sets the maximum time Validate waits for each sink to write the results,
+// a sink exceeding it is reported as failed without holding up the others
+func (v *OpaValidator) WithSinkTimeout(timeout time.Duration) *OpaValidator {
+	v.sinkTimeout = timeout
+	return v
+}
+
+// WithMetrics records validation and sink metrics on the given metrics
+func (v *OpaValidator) WithMetrics(metrics *Metrics) *OpaValidator {
+	v.metrics = metrics
+	return v
+}
+
+// WithTracerProvider traces validations using a tracer from the given provider, tracing is disabled by default
+func (v *OpaValidator) WithTracerProvider(provider trace.TracerProvider) *OpaValidator {
+	v.tracer = provider.Tracer(tracerName)
+	return v
+}
+
+// WithDecisionLogger records a decision log for every validation using the given logger
+func (v *OpaValidator) WithDecisionLogger(decisionLogger DecisionLogger) *OpaValidator {
+	v.decisionLogger = decisionLogger
+	return v
+}
+
+// WithPolicySets restricts the evaluated policies to the ones supporting the given mode, e.g. admission or audit.
+// When any of the policy sets has the same mode, only policies matching those sets are evaluated and
+// the ids of the matching sets are recorded on the results.
+//
+// For backward compatibility, policies without modes support every mode, and when none of the
+// policy sets has the mode, all the policies supporting the mode are evaluated.
+func (v *OpaValidator) WithPolicySets(mode string, policySets ...domain.PolicySet) *OpaValidator {
+	v.mode = mode
+	v.policySets = policySets
+	return v
+}
+
+// Validate validate policies using opa library, implements validation.Validator.
+// Results of entities received in dry run admission requests are not written to sinks.
+func (v *OpaValidator) Validate(ctx context.Context, entity domain.Entity, trigger string) (summary *domain.PolicyValidationSummary, err error) {
+	start := time.Now()
+	ctx, span := startSpan(ctx, v.tracer, "Validate", append(
+		entityAttributes(entity),
+		attribute.String("trigger", trigger),
+	)...)
+	var decisions []PolicyDecision
+	defer func() {
+		if err != nil {
+			recordSpanError(span, err)
+		}
+		span.End()
+		v.metrics.observeValidation(entity, trigger, time.Since(start))
+		v.logDecision(ctx, entity, trigger, decisions, err, start)
+	}()
+
+	policiesCtx, policiesSpan := startSpan(ctx, v.tracer, "GetPolicies")
+	policies, err := v.policiesSource.GetAll(policiesCtx)
 	if err != nil {
+		recordSpanError(policiesSpan, err)
+		policiesSpan.End()
 		return nil, fmt.Errorf("Failed to get policies from source: %w", err)
 	}
+	policiesSpan.SetAttributes(attribute.Int("policies.count", len(policies)))
+	policiesSpan.End()
 
-	config, err := v.policiesSource.GetPolicyConfig(ctx, entity)
+	configCtx, configSpan := startSpan(ctx, v.tracer, "GetPolicyConfig", entityAttributes(entity)...)
+	config, configConflicts, err := v.getPolicyConfig(configCtx, entity)
 	if err != nil {
+		recordSpanError(configSpan, err)
+		configSpan.End()
 		return nil, fmt.Errorf("Failed to get policy config from source: %w", err)
 	}
+	configSpan.End()
 
 	var enqueueGroup sync.WaitGroup
 	var dequeueGroup sync.WaitGroup
@@ -69,6 +149,7 @@ func (v *OpaValidator) Validate(ctx context.Context, entity domain.Entity, trigg
 	compliancesChan := make(chan domain.PolicyValidation, len(policies))
 
 	errsChan := make(chan error, len(policies))
+	decisionsChan := make(chan PolicyDecision, len(policies))
 	bound := make(chan struct{}, maxWorkers)
 
 	for i := range policies {
@@ -80,44 +161,68 @@ func (v *OpaValidator) Validate(ctx context.Context, entity domain.Entity, trigg
 				enqueueGroup.Done()
 			}()
 
-			policy := policies[index]
+			policy := applyPolicyConfig(policies[index], config)
 			if !matchEntity(entity, policy) {
 				return
 			}
+			policySets, ok := v.selectPolicy(policy)
+			if !ok {
+				return
+			}
+
+			_, span := startSpan(ctx, v.tracer, "EvaluatePolicy", append(
+				entityAttributes(entity),
+				attribute.String("policy.id", policy.ID),
+				attribute.String("policy.name", policy.Name),
+			)...)
+			defer span.End()
+
+			decision := newPolicyDecision(policy)
+			decisionStart := time.Now()
+			defer func() {
+				if v.decisionLogger != nil {
+					decision.DurationMs = durationMs(time.Since(decisionStart))
+					decisionsChan <- decision
+				}
+			}()
 
 			opaPolicy, err := opa.Parse(policy.Code, PolicyQuery)
 			if err != nil {
-				errsChan <- fmt.Errorf("failed to parse policy %s: %w", policy.ID, err)
+				err = fmt.Errorf("failed to parse policy %s: %w", policy.ID, err)
+				decision.Status = resultStatusError
+				decision.Error = err.Error()
+				recordSpanError(span, err)
+				v.metrics.countResult(policy, trigger, resultStatusError)
+				errsChan <- err
 				return
 			}
 
-			parameters := map[string]interface{}{}
-			if config == nil {
-				parameters = policy.GetParametersMap()
-			} else {
-				policyConfig, policyConfigExists := config.Config[policy.ID]
-				for i, policyParam := range policy.Parameters {
-					parameters[policyParam.Name] = policyParam.Value
-					if policyConfigExists {
-						if configParam, ok := policyConfig.Parameters[policyParam.Name]; ok {
-							logger.Infow(
-								"overriding parameter",
-								"policy", policy.ID,
-								"parameter", policyParam.Name,
-								"oldValue", policyParam.Value,
-								"newValue", configParam.Value,
-								"configRef", configParam.ConfigRef,
-							)
-							parameters[policyParam.Name] = configParam.Value
-							policy.Parameters[i].Value = configParam.Value
-							policy.Parameters[i].ConfigRef = configParam.ConfigRef
-						}
-					}
-				}
+			parameters, err := policy.ValidateParameters(policy.GetParametersMap())
+			if err != nil {
+				decision.Status = resultStatusError
+				decision.Error = err.Error()
+				recordSpanError(span, err)
+				v.metrics.countResult(policy, trigger, resultStatusError)
+				errsChan <- err
+				return
+			}
+			decision.Parameters = parameters
+
+			input, err := gatekeeperInput(entity, parameters)
+			if err != nil {
+				err = fmt.Errorf("failed to build input of policy %s: %w", policy.ID, err)
+				decision.Status = resultStatusError
+				decision.Error = err.Error()
+				recordSpanError(span, err)
+				v.metrics.countResult(policy, trigger, resultStatusError)
+				errsChan <- err
+				return
 			}
 
 			var opaErr opa.OPAError
-			err = opaPolicy.EvalGateKeeperCompliant(entity.Manifest, parameters, PolicyQuery)
+			evalStart := time.Now()
+			err = opaPolicy.Eval(input, PolicyQuery)
+			v.metrics.observeEvaluation(policy, time.Since(evalStart))
 			if err != nil {
 				if errors.As(err, &opaErr) {
 					dmsg := fmt.Sprintf(
@@ -156,28 +261,41 @@ func (v *OpaValidator) Validate(ctx context.Context, entity domain.Entity, trigg
 						Message:     message,
 						Status:      domain.PolicyValidationStatusViolating,
 						Occurrences: occurrences,
+						PolicySets:  policySets,
 					}
+					decision.Status = result.Status
+					span.SetAttributes(attribute.String("result.status", result.Status))
+					v.metrics.countResult(policy, trigger, domain.PolicyValidationStatusViolating)
 					violationsChan <- result
 
 				} else {
-					errsChan <- fmt.Errorf(
+					err = fmt.Errorf(
 						"unable to evaluate resource against policy. policy id: %s. %w",
 						policy.ID,
 						err)
+					decision.Status = resultStatusError
+					decision.Error = err.Error()
+					recordSpanError(span, err)
+					v.metrics.countResult(policy, trigger, resultStatusError)
+					errsChan <- err
 				}
 
 			} else {
 				result := domain.PolicyValidation{
-					ID:        uuid.NewV4().String(),
-					AccountID: v.accountID,
-					ClusterID: v.clusterID,
-					Policy:    policy,
-					Entity:    entity,
-					Type:      v.validationType,
-					Trigger:   trigger,
-					CreatedAt: time.Now(),
-					Status:    domain.PolicyValidationStatusCompliant,
+					ID:         uuid.NewV4().String(),
+					AccountID:  v.accountID,
+					ClusterID:  v.clusterID,
+					Policy:     policy,
+					Entity:     entity,
+					Type:       v.validationType,
+					Trigger:    trigger,
+					CreatedAt:  time.Now(),
+					Status:     domain.PolicyValidationStatusCompliant,
+					PolicySets: policySets,
 				}
+				decision.Status = result.Status
+				span.SetAttributes(attribute.String("result.status", result.Status))
+				v.metrics.countResult(policy, trigger, domain.PolicyValidationStatusCompliant)
 				compliancesChan <- result
 			}
 		})(i)
@@ -209,10 +327,19 @@ func (v *OpaValidator) Validate(ctx context.Context, entity domain.Entity, trigg
 		}
 	}()
 
+	dequeueGroup.Add(1)
+	go func() {
+		defer dequeueGroup.Done()
+		for decision := range decisionsChan {
+			decisions = append(decisions, decision)
+		}
+	}()
+
 	enqueueGroup.Wait()
 	close(violationsChan)
 	close(compliancesChan)
 	close(errsChan)
+	close(decisionsChan)
 	dequeueGroup.Wait()
 
 	if errs != nil {
@@ -227,45 +354,199 @@ func (v *OpaValidator) Validate(ctx context.Context, entity domain.Entity, trigg
 	var unmutatedViolations []domain.PolicyValidation
 
 	if v.mutate {
-		mutationResult, err = domain.NewMutationResult(entity)
+		mutationResult, unmutatedViolations, err = v.mutateViolations(ctx, entity, violations)
 		if err != nil {
 			return nil, err
 		}
-		for i, violation := range violations {
-			if !violation.Policy.Mutate {
-				continue
-			}
-			occurrences, err := mutationResult.Mutate(violation.Occurrences)
-			if err != nil {
-				return nil, err
-			}
-			var unmutatedOccurrences []domain.Occurrence
-			for _, occurrence := range occurrences {
-				if !occurrence.Mutated {
-					unmutatedOccurrences = append(unmutatedOccurrences, occurrence)
-				}
-			}
-			if len(unmutatedOccurrences) == 0 {
-				continue
-			}
-			violations[i].Occurrences = unmutatedOccurrences
-			unmutatedViolations = append(unmutatedViolations, violation)
-		}
 	} else {
 		unmutatedViolations = violations
 	}
 
 	PolicyValidationSummary := domain.PolicyValidationSummary{
-		Violations:  unmutatedViolations,
-		Compliances: compliances,
-		Mutation:    mutationResult,
+		Violations:      unmutatedViolations,
+		Compliances:     compliances,
+		Mutation:        mutationResult,
+		ConfigConflicts: configConflicts,
 	}
 
-	writeToSinks(ctx, v.resultsSinks, PolicyValidationSummary, v.writeCompliance)
+	// dry run admission requests have no side effects, so their results are not written
+	if entity.Admission != nil && entity.Admission.DryRun {
+		return &PolicyValidationSummary, nil
+	}
+
+	PolicyValidationSummary.SinkStatuses = writeToSinks(ctx, v.tracer, v.resultsSinks, PolicyValidationSummary, v.writeCompliance, v.sinkTimeout)
+	v.metrics.countSinkWrites(PolicyValidationSummary.SinkStatuses)
+	if v.failOnSinkError {
+		if err := PolicyValidationSummary.SinkErrors(); err != nil {
+			return &PolicyValidationSummary, fmt.Errorf("failed to write validation results to sinks: %w", err)
+		}
+	}
 
 	return &PolicyValidationSummary, nil
 }
 
+// getPolicyConfig returns the policy config of the entity, the configs of sources implementing
+// domain.PolicyConfigsSource matching the entity are resolved by precedence and their conflicts are returned
+func (v *OpaValidator) getPolicyConfig(ctx context.Context, entity domain.Entity) (*domain.PolicyConfig, []domain.PolicyConfigConflict, error) {
+	source, ok := v.policiesSource.(domain.PolicyConfigsSource)
+	if !ok {
+		config, err := v.policiesSource.GetPolicyConfig(ctx, entity)
+		return config, nil, err
+	}
+
+	configs, err := source.GetPolicyConfigs(ctx, entity)
+	if err != nil {
+		return nil, nil, err
+	}
+	config, conflicts := domain.ResolveEntityPolicyConfigs(configs, entity)
+	for _, conflict := range conflicts {
+		logger.Warnw(
+			"conflicting policy configs",
+			"entity", entity.Name,
+			"policy", conflict.PolicyID,
+			"parameter", conflict.Parameter,
+			"level", conflict.Level.String(),
+			"configRefs", conflict.ConfigRefs,
+		)
+	}
+	return config, conflicts, nil
+}
+
+// selectPolicy checks if the policy should be evaluated in the validator mode,
+// it returns the ids of the policy sets of the mode matching the policy
+func (v *OpaValidator) selectPolicy(policy domain.Policy) ([]string, bool) {
+	if v.mode == "" {
+		return nil, true
+	}
+	if len(policy.Modes) > 0 && !contains(policy.Modes, v.mode) {
+		return nil, false
+	}
+
+	var modeSets bool
+	var policySets []string
+	for i := range v.policySets {
+		if v.policySets[i].Mode != v.mode {
+			continue
+		}
+		modeSets = true
+		if v.policySets[i].Match(policy) {
+			policySets = append(policySets, v.policySets[i].ID)
+		}
+	}
+	if modeSets && len(policySets) == 0 {
+		return nil, false
+	}
+	return policySets, true
+}
+
+// applyPolicyConfig returns a copy of the policy with the parameters overridden by the policy config,
+// the policy itself is left untouched as it may be shared by concurrent validations
+func applyPolicyConfig(policy domain.Policy, config *domain.PolicyConfig) domain.Policy {
+	policy.Parameters = append([]domain.PolicyParameters(nil), policy.Parameters...)
+	if config == nil {
+		return policy
+	}
+
+	policyConfig, ok := config.Config[policy.ID]
+	if !ok {
+		return policy
+	}
+	for i, policyParam := range policy.Parameters {
+		if configParam, ok := policyConfig.Parameters[policyParam.Name]; ok {
+			logger.Infow(
+				"overriding parameter",
+				"policy", policy.ID,
+				"parameter", policyParam.Name,
+				"oldValue", policyParam.Value,
+				"newValue", configParam.Value,
+				"configRef", configParam.ConfigRef,
+			)
+			policy.Parameters[i].Value = configParam.Value
+			policy.Parameters[i].ConfigRef = configParam.ConfigRef
+		}
+	}
+	return policy
+}
+
+// logDecision writes the decision log of a validation if a decision logger is set
+func (v *OpaValidator) logDecision(
+	ctx context.Context,
+	entity domain.Entity,
+	trigger string,
+	decisions []PolicyDecision,
+	err error,
+	start time.Time,
+) {
+	if v.decisionLogger == nil {
+		return
+	}
+	sort.Slice(decisions, func(i, j int) bool {
+		return decisions[i].PolicyID < decisions[j].PolicyID
+	})
+	decision := DecisionLog{
+		Timestamp:  start,
+		AccountID:  v.accountID,
+		ClusterID:  v.clusterID,
+		Type:       v.validationType,
+		Trigger:    trigger,
+		Entity:     entity.ObjectRef(),
+		Policies:   decisions,
+		DurationMs: durationMs(time.Since(start)),
+	}
+	if err != nil {
+		decision.Error = err.Error()
+	}
+	if err := v.decisionLogger.Log(ctx, decision); err != nil {
+		logger.Errorw("failed to write decision log", "entity", entity.Name, "error", err)
+	}
+}
+
+// mutateViolations applies the recommended values of mutating policies violations to the entity,
+// returns the mutation result and the violations that still have unmutated occurrences
+func (v *OpaValidator) mutateViolations(
+	ctx context.Context,
+	entity domain.Entity,
+	violations []domain.PolicyValidation,
+) (*domain.MutationResult, []domain.PolicyValidation, error) {
+	_, span := startSpan(ctx, v.tracer, "Mutate", entityAttributes(entity)...)
+	defer span.End()
+
+	mutationResult, err := domain.NewMutationResult(entity)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, nil, err
+	}
+
+	var unmutatedViolations []domain.PolicyValidation
+	for i, violation := range violations {
+		// violations of policies that do not mutate are kept as is so they are still enforced
+		if !violation.Policy.Mutate {
+			unmutatedViolations = append(unmutatedViolations, violation)
+			continue
+		}
+		occurrences, err := mutationResult.Mutate(violation.Occurrences)
+		if err != nil {
+			recordSpanError(span, err)
+			return nil, nil, err
+		}
+		var unmutatedOccurrences []domain.Occurrence
+		for _, occurrence := range occurrences {
+			if !occurrence.Mutated {
+				unmutatedOccurrences = append(unmutatedOccurrences, occurrence)
+			}
+		}
+		if len(unmutatedOccurrences) < len(occurrences) {
+			v.metrics.countMutation(violation.Policy)
+		}
+		if len(unmutatedOccurrences) == 0 {
+			continue
+		}
+		violations[i].Occurrences = unmutatedOccurrences
+		unmutatedViolations = append(unmutatedViolations, violations[i])
+	}
+	return mutationResult, unmutatedViolations, nil
+}
+
 func parseOccurrence(msg string, in interface{}) domain.Occurrence {
 	occurrence := domain.Occurrence{Message: msg}
 	if v, ok := in.(map[string]interface{}); ok {
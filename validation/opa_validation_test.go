@@ -5,13 +5,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/MagalixTechnologies/policy-core/domain"
 	"github.com/MagalixTechnologies/policy-core/domain/mock"
+	"github.com/MagalixTechnologies/policy-core/sink"
 	"github.com/MagalixTechnologies/policy-core/validation/testdata"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 func TestNewOPAValidator(t *testing.T) {
@@ -676,3 +681,529 @@ func TestOpaValidator_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestOpaValidator_SinkErrors(t *testing.T) {
+	assert := require.New(t)
+	entity, err := getEntityFromStringSpec(testdata.Entity)
+	assert.Nil(err)
+
+	tests := []struct {
+		name            string
+		sinkErr         error
+		failOnSinkError bool
+		wantErr         bool
+		delivered       int
+	}{
+		{
+			name:      "sink succeeds",
+			delivered: 1,
+		},
+		{
+			name:    "sink fails without propagation",
+			sinkErr: fmt.Errorf("sink unavailable"),
+		},
+		{
+			name:            "sink fails with propagation",
+			sinkErr:         fmt.Errorf("sink unavailable"),
+			failOnSinkError: true,
+			wantErr:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			policiesSource := mock.NewMockPoliciesSource(ctrl)
+			sink := mock.NewMockPolicyValidationSink(ctrl)
+			policiesSource.EXPECT().GetAll(gomock.Any()).
+				Times(1).Return([]domain.Policy{testdata.Policies["missingOwner"]}, nil)
+			policiesSource.EXPECT().GetPolicyConfig(gomock.Any(), gomock.Any()).
+				Times(1).Return(nil, nil)
+			sink.EXPECT().Write(gomock.Any(), gomock.Any()).
+				Times(1).Return(tt.sinkErr)
+
+			v := NewOPAValidator(policiesSource, false, "unit-test", "", "", false, sink).
+				WithFailOnSinkError(tt.failOnSinkError)
+			got, err := v.Validate(context.Background(), entity, "unit-test")
+			if tt.wantErr {
+				assert.Error(err)
+			} else {
+				assert.Nil(err)
+			}
+
+			assert.NotNil(got)
+			assert.Len(got.SinkStatuses, 1)
+			assert.Equal(tt.delivered, got.SinkStatuses[0].Delivered)
+			if tt.sinkErr != nil {
+				assert.Error(got.SinkStatuses[0].Error)
+				assert.Error(got.SinkErrors())
+			} else {
+				assert.Nil(got.SinkStatuses[0].Error)
+				assert.Nil(got.SinkErrors())
+			}
+		})
+	}
+}
+
+func TestOpaValidator_SinkNames(t *testing.T) {
+	assert := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	entity, err := getEntityFromStringSpec(testdata.Entity)
+	assert.Nil(err)
+	policiesSource := mock.NewMockPoliciesSource(ctrl)
+	policiesSource.EXPECT().GetAll(gomock.Any()).
+		Times(1).Return([]domain.Policy{testdata.Policies["missingOwner"]}, nil)
+	policiesSource.EXPECT().GetPolicyConfig(gomock.Any(), gomock.Any()).
+		Times(1).Return(nil, nil)
+
+	var resultsSinks []domain.PolicyValidationSink
+	for i := 0; i < 3; i++ {
+		resultsSink := mock.NewMockPolicyValidationSink(ctrl)
+		resultsSink.EXPECT().Write(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+		resultsSinks = append(resultsSinks, resultsSink)
+	}
+	resultsSinks[0] = sink.NewNamedSink("audit-webhook", resultsSinks[0])
+	resultsSinks[1] = sink.NewNamedSink("alerts-webhook", resultsSinks[1])
+
+	v := NewOPAValidator(policiesSource, false, "unit-test", "", "", false, resultsSinks...)
+	got, err := v.Validate(context.Background(), entity, "unit-test")
+	assert.Nil(err)
+
+	var names []string
+	for _, status := range got.SinkStatuses {
+		names = append(names, status.Sink)
+	}
+	assert.Equal([]string{"audit-webhook", "alerts-webhook", "*mock.MockPolicyValidationSink"}, names)
+}
+
+func TestOpaValidator_ValidateParameters(t *testing.T) {
+	entity, err := getEntityFromStringSpec(testdata.Entity)
+	require.Nil(t, err)
+
+	tests := []struct {
+		name       string
+		value      interface{}
+		violations int
+		wantErr    string
+	}{
+		{
+			name:  "coerce string to integer",
+			value: "3",
+		},
+		{
+			name:       "coerce string to integer with violation",
+			value:      "5",
+			violations: 1,
+		},
+		{
+			name:    "wrong parameter type",
+			value:   []string{"3"},
+			wantErr: "parameter replica_count expects a value of type integer",
+		},
+		{
+			name:    "missing required parameter",
+			value:   nil,
+			wantErr: "parameter replica_count is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := require.New(t)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			replicaCount := testdata.Policies["replicaCount"]
+			policiesSource := mock.NewMockPoliciesSource(ctrl)
+			policiesSource.EXPECT().GetAll(gomock.Any()).
+				Times(1).Return([]domain.Policy{replicaCount}, nil)
+			policiesSource.EXPECT().GetPolicyConfig(gomock.Any(), gomock.Any()).
+				Times(1).Return(&domain.PolicyConfig{
+				Config: map[string]domain.PolicyConfigConfig{
+					replicaCount.ID: {
+						Parameters: map[string]domain.PolicyConfigParameter{
+							"replica_count": {Value: tt.value, ConfigRef: "my-config"},
+						},
+					},
+				},
+			}, nil)
+
+			v := NewOPAValidator(policiesSource, false, "unit-test", "", "", false)
+			got, err := v.Validate(context.Background(), entity, "unit-test")
+			if tt.wantErr != "" {
+				assert.Error(err)
+				assert.Contains(err.Error(), replicaCount.ID)
+				assert.Contains(err.Error(), tt.wantErr)
+				return
+			}
+			assert.Nil(err)
+			assert.Len(got.Violations, tt.violations)
+		})
+	}
+}
+
+func TestOpaValidator_PolicySets(t *testing.T) {
+	entity, err := getEntityFromStringSpec(testdata.Entity)
+	require.Nil(t, err)
+
+	imageTag := testdata.Policies["imageTag"]
+	missingOwner := testdata.Policies["missingOwner"]
+	missingOwner.Modes = []string{domain.PolicyModeAdmission}
+	runningAsRoot := testdata.Policies["runningAsRoot"]
+	runningAsRoot.Modes = []string{domain.PolicyModeAudit, domain.PolicyModeAdmission}
+
+	auditSet := domain.PolicySet{
+		ID:   "audit-set",
+		Mode: domain.PolicyModeAudit,
+		Filters: domain.PolicySetFilters{
+			IDs: []string{imageTag.ID, missingOwner.ID},
+		},
+	}
+	admissionSet := domain.PolicySet{
+		ID:   "admission-set",
+		Mode: domain.PolicyModeAdmission,
+		Filters: domain.PolicySetFilters{
+			IDs: []string{missingOwner.ID, runningAsRoot.ID},
+		},
+	}
+	allSet := domain.PolicySet{
+		ID:   "all-set",
+		Mode: domain.PolicyModeAdmission,
+		Filters: domain.PolicySetFilters{
+			IDs: []string{imageTag.ID, missingOwner.ID, runningAsRoot.ID},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		mode       string
+		policySets []domain.PolicySet
+		// want maps the evaluated policies to the ids of the sets that selected them
+		want map[string][]string
+	}{
+		{
+			name: "no mode",
+			want: map[string][]string{
+				imageTag.ID:      nil,
+				missingOwner.ID:  nil,
+				runningAsRoot.ID: nil,
+			},
+		},
+		{
+			// imageTag has no modes, so it is evaluated in any mode when its set matches
+			name:       "audit mode",
+			mode:       domain.PolicyModeAudit,
+			policySets: []domain.PolicySet{auditSet, admissionSet},
+			want: map[string][]string{
+				imageTag.ID: {"audit-set"},
+			},
+		},
+		{
+			name:       "admission mode",
+			mode:       domain.PolicyModeAdmission,
+			policySets: []domain.PolicySet{auditSet, admissionSet, allSet},
+			want: map[string][]string{
+				imageTag.ID:      {"all-set"},
+				missingOwner.ID:  {"admission-set", "all-set"},
+				runningAsRoot.ID: {"admission-set", "all-set"},
+			},
+		},
+		{
+			name:       "no policy sets of the mode evaluates all policies supporting the mode",
+			mode:       domain.PolicyModeAudit,
+			policySets: []domain.PolicySet{admissionSet},
+			want: map[string][]string{
+				imageTag.ID:      nil,
+				runningAsRoot.ID: nil,
+			},
+		},
+		{
+			name: "mode without policy sets",
+			mode: domain.PolicyModeAdmission,
+			want: map[string][]string{
+				imageTag.ID:      nil,
+				missingOwner.ID:  nil,
+				runningAsRoot.ID: nil,
+			},
+		},
+		{
+			name:       "policies without modes are skipped when not in a set of the mode",
+			mode:       domain.PolicyModeAdmission,
+			policySets: []domain.PolicySet{admissionSet},
+			want: map[string][]string{
+				missingOwner.ID:  {"admission-set"},
+				runningAsRoot.ID: {"admission-set"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := require.New(t)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			policiesSource := mock.NewMockPoliciesSource(ctrl)
+			policiesSource.EXPECT().GetAll(gomock.Any()).
+				Times(1).Return([]domain.Policy{imageTag, missingOwner, runningAsRoot}, nil)
+			policiesSource.EXPECT().GetPolicyConfig(gomock.Any(), gomock.Any()).
+				Times(1).Return(nil, nil)
+
+			v := NewOPAValidator(policiesSource, false, "unit-test", "", "", false).
+				WithPolicySets(tt.mode, tt.policySets...)
+			summary, err := v.Validate(context.Background(), entity, "unit-test")
+			assert.Nil(err)
+
+			got := map[string][]string{}
+			for _, result := range append(summary.Violations, summary.Compliances...) {
+				got[result.Policy.ID] = result.PolicySets
+			}
+			assert.Equal(tt.want, got)
+		})
+	}
+}
+
+// configsSource is a policies source returning multiple policy configs
+type configsSource struct {
+	*mock.MockPoliciesSource
+	configs []domain.PolicyConfig
+}
+
+func (s *configsSource) GetPolicyConfigs(ctx context.Context, entity domain.Entity) ([]domain.PolicyConfig, error) {
+	return s.configs, nil
+}
+
+func TestOpaValidator_PolicyConfigs(t *testing.T) {
+	assert := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	entity, err := getEntityFromStringSpec(testdata.Entity)
+	assert.Nil(err)
+
+	replicaCount := testdata.Policies["replicaCount"]
+	config := func(id string, match domain.PolicyConfigMatch, value interface{}) domain.PolicyConfig {
+		return domain.PolicyConfig{
+			ID:    id,
+			Match: match,
+			Config: map[string]domain.PolicyConfigConfig{
+				replicaCount.ID: {
+					Parameters: map[string]domain.PolicyConfigParameter{
+						"replica_count": {Value: value},
+					},
+				},
+			},
+		}
+	}
+	namespace := domain.PolicyConfigMatch{Namespaces: []string{entity.Namespace}}
+	resource := domain.PolicyConfigMatch{Resources: []domain.ConfigMatchResource{{Kind: entity.Kind, Name: entity.Name}}}
+	otherResource := domain.PolicyConfigMatch{Resources: []domain.ConfigMatchResource{{Kind: entity.Kind, Name: "other"}}}
+
+	policiesSource := mock.NewMockPoliciesSource(ctrl)
+	policiesSource.EXPECT().GetAll(gomock.Any()).
+		Times(2).Return([]domain.Policy{replicaCount}, nil)
+	source := &configsSource{
+		MockPoliciesSource: policiesSource,
+		configs: []domain.PolicyConfig{
+			config("cluster", domain.PolicyConfigMatch{}, 5),
+			config("resource", resource, 3),
+			config("namespace", namespace, 4),
+			config("other-resource", otherResource, 10),
+		},
+	}
+
+	v := NewOPAValidator(source, false, "unit-test", "", "", false)
+	summary, err := v.Validate(context.Background(), entity, "unit-test")
+	assert.Nil(err)
+	assert.Empty(summary.Violations)
+	assert.Len(summary.Compliances, 1)
+	assert.Empty(summary.ConfigConflicts)
+	for _, param := range summary.Compliances[0].Policy.Parameters {
+		if param.Name == "replica_count" {
+			assert.Equal(3, param.Value)
+			assert.Equal("resource", param.ConfigRef)
+		}
+	}
+
+	source.configs = []domain.PolicyConfig{
+		config("cluster", domain.PolicyConfigMatch{}, 3),
+		config("namespace-b", namespace, 4),
+		config("namespace-a", namespace, 5),
+	}
+	summary, err = v.Validate(context.Background(), entity, "unit-test")
+	assert.Nil(err)
+	assert.Len(summary.Violations, 1)
+	assert.Len(summary.ConfigConflicts, 1)
+	assert.Equal([]string{"namespace-a", "namespace-b"}, summary.ConfigConflicts[0].ConfigRefs)
+}
+
+func TestOpaValidator_ConcurrentConfigs(t *testing.T) {
+	assert := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	entity, err := getEntityFromStringSpec(testdata.Entity)
+	assert.Nil(err)
+	otherEntity, err := getEntityFromStringSpec(testdata.Entity)
+	assert.Nil(err)
+	otherEntity.Name = "other-deployment"
+
+	// the source serves the same cached policies to every validation
+	policies := []domain.Policy{testdata.Policies["replicaCount"]}
+	defaultValue := policies[0].Parameters[0].Value
+	replicaCountConfig := func(value interface{}) *domain.PolicyConfig {
+		return &domain.PolicyConfig{
+			Config: map[string]domain.PolicyConfigConfig{
+				policies[0].ID: {
+					Parameters: map[string]domain.PolicyConfigParameter{
+						"replica_count": {Value: value, ConfigRef: fmt.Sprintf("config-%v", value)},
+					},
+				},
+			},
+		}
+	}
+
+	policiesSource := mock.NewMockPoliciesSource(ctrl)
+	policiesSource.EXPECT().GetAll(gomock.Any()).AnyTimes().Return(policies, nil)
+	policiesSource.EXPECT().GetPolicyConfig(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(ctx context.Context, entity domain.Entity) (*domain.PolicyConfig, error) {
+			if entity.Name == otherEntity.Name {
+				return replicaCountConfig(5), nil
+			}
+			return replicaCountConfig(2), nil
+		})
+
+	v := NewOPAValidator(policiesSource, false, "unit-test", "", "", false)
+
+	const runs = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*runs)
+	for i := 0; i < runs; i++ {
+		for _, e := range []domain.Entity{entity, otherEntity} {
+			wg.Add(1)
+			go func(e domain.Entity) {
+				defer wg.Done()
+				summary, err := v.Validate(context.Background(), e, "unit-test")
+				if err != nil {
+					errs <- err
+					return
+				}
+				wantViolations, wantRef := 0, "config-2"
+				if e.Name == otherEntity.Name {
+					wantViolations, wantRef = 1, "config-5"
+				}
+				if len(summary.Violations) != wantViolations {
+					errs <- fmt.Errorf("entity %s: expected %d violations, found %d", e.Name, wantViolations, len(summary.Violations))
+					return
+				}
+				results := append(summary.Violations, summary.Compliances...)
+				if ref := results[0].Policy.Parameters[0].ConfigRef; ref != wantRef {
+					errs <- fmt.Errorf("entity %s: expected config ref %s, found %s", e.Name, wantRef, ref)
+				}
+			}(e)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.Nil(err)
+	}
+
+	assert.Equal(defaultValue, policies[0].Parameters[0].Value)
+	assert.Empty(policies[0].Parameters[0].ConfigRef)
+}
+
+func TestOpaValidator_AdmissionOldObject(t *testing.T) {
+	scaleDown := domain.Policy{
+		ID:   "scale-down",
+		Name: "Scaling down",
+		Code: `
+		package weave.test.scale_down
+
+		violation[result] {
+			input.review.operation == "UPDATE"
+			input.review.userInfo.username != "admin"
+			input.review.object.spec.replicas < input.review.oldObject.spec.replicas
+			result := {"msg": sprintf("%s scaled down %s", [input.review.userInfo.username, input.review.name])}
+		}
+		`,
+	}
+	object := `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "app", "namespace": "default"}, "spec": {"replicas": %d}}`
+
+	tests := []struct {
+		name       string
+		operation  admissionv1.Operation
+		username   string
+		replicas   int
+		violations int
+	}{
+		{name: "scale down", operation: admissionv1.Update, username: "jane", replicas: 1, violations: 1},
+		{name: "scale down by admin", operation: admissionv1.Update, username: "admin", replicas: 1},
+		{name: "scale up", operation: admissionv1.Update, username: "jane", replicas: 5},
+		{name: "create", operation: admissionv1.Create, username: "jane", replicas: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := require.New(t)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			req := &admissionv1.AdmissionRequest{
+				UID:       "1",
+				Operation: tt.operation,
+				UserInfo:  authenticationv1.UserInfo{Username: tt.username},
+				Object:    runtime.RawExtension{Raw: []byte(fmt.Sprintf(object, tt.replicas))},
+			}
+			if tt.operation == admissionv1.Update {
+				req.OldObject = runtime.RawExtension{Raw: []byte(fmt.Sprintf(object, 3))}
+			}
+			entity, err := domain.NewEntityFromAdmissionRequest(req)
+			assert.Nil(err)
+
+			policiesSource := mock.NewMockPoliciesSource(ctrl)
+			policiesSource.EXPECT().GetAll(gomock.Any()).Times(1).Return([]domain.Policy{scaleDown}, nil)
+			policiesSource.EXPECT().GetPolicyConfig(gomock.Any(), gomock.Any()).Times(1).Return(nil, nil)
+
+			v := NewOPAValidator(policiesSource, false, "unit-test", "", "", false)
+			summary, err := v.Validate(context.Background(), entity, "Admission")
+			assert.Nil(err)
+			assert.Len(summary.Violations, tt.violations)
+			if tt.violations > 0 {
+				assert.Equal("jane scaled down app", summary.Violations[0].Occurrences[0].Message)
+			}
+		})
+	}
+}
+
+func TestOpaValidator_DryRun(t *testing.T) {
+	assert := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	entity, err := getEntityFromStringSpec(testdata.Entity)
+	assert.Nil(err)
+
+	policiesSource := mock.NewMockPoliciesSource(ctrl)
+	policiesSource.EXPECT().GetAll(gomock.Any()).Times(2).
+		Return([]domain.Policy{testdata.Policies["imageTag"]}, nil)
+	policiesSource.EXPECT().GetPolicyConfig(gomock.Any(), gomock.Any()).Times(2).Return(nil, nil)
+	sink := mock.NewMockPolicyValidationSink(ctrl)
+	sink.EXPECT().Write(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+
+	v := NewOPAValidator(policiesSource, true, "unit-test", "", "", false, sink)
+
+	entity.Admission = &domain.EntityAdmission{Operation: "CREATE", DryRun: true}
+	summary, err := v.Validate(context.Background(), entity, "Admission")
+	assert.Nil(err)
+	assert.Len(summary.Violations, 1)
+	assert.Empty(summary.SinkStatuses)
+
+	entity.Admission.DryRun = false
+	summary, err = v.Validate(context.Background(), entity, "Admission")
+	assert.Nil(err)
+	assert.Len(summary.SinkStatuses, 1)
+}
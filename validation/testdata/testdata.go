@@ -425,7 +425,7 @@ var (
 				{
 					Name:     "exclude_namespaces",
 					Type:     "array",
-					Required: true,
+					Required: false,
 					Value:    nil,
 				},
 				{
@@ -0,0 +1,36 @@
+package validation
+
+import (
+	"context"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/MagalixTechnologies/policy-core/validation"
+
+var noopTracer = trace.NewNoopTracerProvider().Tracer(tracerName)
+
+// startSpan starts a span using the given tracer, or a no-op span when tracer is nil
+func startSpan(ctx context.Context, tracer trace.Tracer, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if tracer == nil {
+		tracer = noopTracer
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// recordSpanError marks the span as failed with the given error
+func recordSpanError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+func entityAttributes(entity domain.Entity) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("entity.kind", entity.Kind),
+		attribute.String("entity.name", entity.Name),
+		attribute.String("entity.namespace", entity.Namespace),
+	}
+}
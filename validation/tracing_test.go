@@ -0,0 +1,98 @@
+package validation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MagalixTechnologies/policy-core/domain"
+	"github.com/MagalixTechnologies/policy-core/domain/mock"
+	"github.com/MagalixTechnologies/policy-core/validation/testdata"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestOpaValidator_Tracing(t *testing.T) {
+	assert := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	entity, err := getEntityFromStringSpec(testdata.Entity)
+	assert.Nil(err)
+	missingOwner := testdata.Policies["missingOwner"]
+
+	policiesSource := mock.NewMockPoliciesSource(ctrl)
+	policiesSource.EXPECT().GetAll(gomock.Any()).
+		Times(1).Return([]domain.Policy{missingOwner, testdata.Policies["badPolicyCode"]}, nil)
+	policiesSource.EXPECT().GetPolicyConfig(gomock.Any(), gomock.Any()).
+		Times(1).Return(nil, nil)
+	sink := mock.NewMockPolicyValidationSink(ctrl)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	v := NewOPAValidator(policiesSource, false, "unit-test", "", "", true, sink).WithTracerProvider(provider)
+	_, err = v.Validate(context.Background(), entity, "admission")
+	assert.Error(err)
+
+	spans := map[string][]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = append(spans[span.Name()], span)
+	}
+	assert.Len(spans["Validate"], 1)
+	assert.Len(spans["GetPolicies"], 1)
+	assert.Len(spans["GetPolicyConfig"], 1)
+	assert.Len(spans["EvaluatePolicy"], 2)
+
+	root := spans["Validate"][0]
+	for _, name := range []string{"GetPolicies", "GetPolicyConfig", "EvaluatePolicy"} {
+		for _, span := range spans[name] {
+			assert.Equal(root.SpanContext().SpanID(), span.Parent().SpanID())
+		}
+	}
+
+	var failed int
+	for _, span := range spans["EvaluatePolicy"] {
+		attrs := attribute.NewSet(span.Attributes()...)
+		kind, _ := attrs.Value("entity.kind")
+		assert.Equal("Deployment", kind.AsString())
+		if len(span.Events()) > 0 {
+			failed++
+		}
+	}
+	assert.Equal(1, failed)
+	assert.NotEmpty(root.Events())
+}
+
+func TestOpaValidator_TracingMutationAndSinks(t *testing.T) {
+	assert := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	entity, err := getEntityFromStringSpec(testdata.Entity)
+	assert.Nil(err)
+
+	policiesSource := mock.NewMockPoliciesSource(ctrl)
+	policiesSource.EXPECT().GetAll(gomock.Any()).
+		Times(1).Return([]domain.Policy{testdata.Policies["imageTag"]}, nil)
+	policiesSource.EXPECT().GetPolicyConfig(gomock.Any(), gomock.Any()).
+		Times(1).Return(nil, nil)
+	sink := mock.NewMockPolicyValidationSink(ctrl)
+	sink.EXPECT().Write(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	v := NewOPAValidator(policiesSource, false, "unit-test", "", "", true, sink).WithTracerProvider(provider)
+	_, err = v.Validate(context.Background(), entity, "admission")
+	assert.Nil(err)
+
+	names := map[string]int{}
+	for _, span := range recorder.Ended() {
+		names[span.Name()]++
+	}
+	assert.Equal(1, names["Mutate"])
+	assert.Equal(1, names["WriteSink"])
+}